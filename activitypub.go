@@ -0,0 +1,627 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tristenkelly/chirpy/internal/activitypub"
+	"github.com/tristenkelly/chirpy/internal/database"
+)
+
+const (
+	apOutboxSize   = 20
+	maxInboxBytes  = 1 << 20
+	remoteActorTTL = 24 * time.Hour
+
+	notificationRemoteFollow = "remote_follow"
+	notificationRemoteLike   = "remote_like"
+)
+
+// Federation is on when PUBLIC_URL is set. Users are known to the
+// fediverse by their ID, as acct:<id>@<host>; chirps are Notes.
+
+func (cfg *apiConfig) actorURL(userID uuid.UUID) string {
+	return cfg.publicURL + "/ap/users/" + userID.String()
+}
+
+func (cfg *apiConfig) noteURL(chirpID uuid.UUID) string {
+	return cfg.publicURL + "/ap/chirps/" + chirpID.String()
+}
+
+func (cfg *apiConfig) followActivityURL(followID uuid.UUID) string {
+	return cfg.publicURL + "/ap/follows/" + followID.String()
+}
+
+// localID extracts the ID from one of our own URLs with the given path
+// prefix, such as a note URL in an incoming Like.
+func (cfg *apiConfig) localID(rawURL, prefix string) (uuid.UUID, bool) {
+	rest, ok := strings.CutPrefix(rawURL, cfg.publicURL+prefix)
+	if !ok {
+		return uuid.UUID{}, false
+	}
+	id, err := uuid.Parse(rest)
+	return id, err == nil
+}
+
+// federationHost is the host part of acct: handles.
+func (cfg *apiConfig) federationHost() string {
+	u, err := url.Parse(cfg.publicURL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// federating writes a 404 when federation is turned off.
+func (cfg *apiConfig) federating(w http.ResponseWriter) bool {
+	if cfg.publicURL == "" {
+		w.WriteHeader(404)
+		return false
+	}
+	return true
+}
+
+// federatedUser loads the {userID} user for an ActivityPub endpoint,
+// writing the error status itself. Suspended users aren't served.
+func (cfg *apiConfig) federatedUser(w http.ResponseWriter, r *http.Request) (database.User, bool) {
+	if !cfg.federating(w) {
+		return database.User{}, false
+	}
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		w.WriteHeader(404)
+		return database.User{}, false
+	}
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil || user.SuspendedAt.Valid {
+		w.WriteHeader(404)
+		return database.User{}, false
+	}
+	return user, true
+}
+
+func writeActivityJSON(w http.ResponseWriter, v any) {
+	val, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("error marshalling activity: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.Header().Set("Content-Type", activitypub.ContentType)
+	w.WriteHeader(200)
+	w.Write(val)
+}
+
+// actorKey returns the user's signing key, creating it on first use.
+func (cfg *apiConfig) actorKey(ctx context.Context, userID uuid.UUID) (database.ActorKey, error) {
+	key, err := cfg.db.GetActorKey(ctx, userID)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return key, err
+	}
+	privatePEM, publicPEM, err := activitypub.GenerateKey()
+	if err != nil {
+		return database.ActorKey{}, err
+	}
+	// If another request created a key first, keep theirs.
+	err = cfg.db.CreateActorKey(ctx, database.CreateActorKeyParams{
+		UserID:        userID,
+		CreatedAt:     time.Now(),
+		PrivateKeyPem: privatePEM,
+		PublicKeyPem:  publicPEM,
+	})
+	if err != nil {
+		return database.ActorKey{}, err
+	}
+	return cfg.db.GetActorKey(ctx, userID)
+}
+
+// remoteActor returns a remote actor, fetching it when we haven't seen it
+// before, when the cached copy is old, or when refresh is set.
+func (cfg *apiConfig) remoteActor(ctx context.Context, actorID string, refresh bool) (database.RemoteActor, error) {
+	if !refresh {
+		actor, err := cfg.db.GetRemoteActor(ctx, actorID)
+		if err == nil && time.Since(fromTimestamp(actor.FetchedAt)) < remoteActorTTL {
+			return actor, nil
+		}
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return database.RemoteActor{}, err
+		}
+	}
+	fetched, err := cfg.apClient.FetchActor(ctx, actorID)
+	if err != nil {
+		return database.RemoteActor{}, err
+	}
+	sharedInbox := ""
+	if fetched.Endpoints != nil {
+		sharedInbox = fetched.Endpoints.SharedInbox
+	}
+	return cfg.db.UpsertRemoteActor(ctx, database.UpsertRemoteActorParams{
+		ID:             fetched.ID,
+		FetchedAt:      time.Now(),
+		InboxUrl:       fetched.Inbox,
+		SharedInboxUrl: sharedInbox,
+		PublicKeyID:    fetched.PublicKey.ID,
+		PublicKeyPem:   fetched.PublicKey.PublicKeyPem,
+	})
+}
+
+func (cfg *apiConfig) webfinger(w http.ResponseWriter, r *http.Request) {
+	if !cfg.federating(w) {
+		return
+	}
+	resource := r.URL.Query().Get("resource")
+	var userID uuid.UUID
+	var err error
+	if account, ok := strings.CutPrefix(resource, "acct:"); ok {
+		name, host, _ := strings.Cut(account, "@")
+		if !strings.EqualFold(host, cfg.federationHost()) {
+			w.WriteHeader(404)
+			return
+		}
+		userID, err = uuid.Parse(name)
+	} else if id, ok := cfg.localID(resource, "/ap/users/"); ok {
+		userID = id
+	} else {
+		err = errors.New("unknown resource")
+	}
+	if err != nil {
+		w.WriteHeader(404)
+		return
+	}
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil || user.SuspendedAt.Valid {
+		w.WriteHeader(404)
+		return
+	}
+
+	actor := cfg.actorURL(user.ID)
+	val, err := json.Marshal(activitypub.WebFinger{
+		Subject: "acct:" + user.ID.String() + "@" + cfg.federationHost(),
+		Aliases: []string{actor},
+		Links: []activitypub.WebFingerLink{
+			{Rel: "self", Type: activitypub.ContentType, Href: actor},
+		},
+	})
+	if err != nil {
+		log.Printf("error marshalling webfinger: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/jrd+json")
+	w.WriteHeader(200)
+	w.Write(val)
+}
+
+func (cfg *apiConfig) getActor(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.federatedUser(w, r)
+	if !ok {
+		return
+	}
+	key, err := cfg.actorKey(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("error getting actor key: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	actor := cfg.actorURL(user.ID)
+	writeActivityJSON(w, activitypub.Actor{
+		Context:           []string{activitypub.Context, activitypub.SecurityV1},
+		ID:                actor,
+		Type:              "Person",
+		PreferredUsername: user.ID.String(),
+		Inbox:             actor + "/inbox",
+		Outbox:            actor + "/outbox",
+		Followers:         actor + "/followers",
+		PublicKey: activitypub.PublicKey{
+			ID:           cfg.keyID(user.ID),
+			Owner:        actor,
+			PublicKeyPem: key.PublicKeyPem,
+		},
+	})
+}
+
+// getOutbox lists the user's most recent public chirps as Create
+// activities.
+func (cfg *apiConfig) getOutbox(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.federatedUser(w, r)
+	if !ok {
+		return
+	}
+	total, err := cfg.db.CountPublicChirpsForUser(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("error counting chirps: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	chirps, err := cfg.db.GetPublicChirpsForUser(r.Context(), database.GetPublicChirpsForUserParams{
+		UserID: user.ID,
+		Limit:  apOutboxSize,
+	})
+	if err != nil {
+		log.Printf("error getting chirps: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	resp, err := cfg.chirpResponses(r.Context(), uuid.Nil, chirps)
+	if err != nil {
+		log.Printf("error building chirp responses: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	items := []any{}
+	for _, chirp := range resp {
+		note := cfg.chirpNote(chirp)
+		create, err := activitypub.NewActivity(note.ID+"/activity", "Create", note.AttributedTo, note, note.To, note.Cc)
+		if err != nil {
+			log.Printf("error building activity: %v", err)
+			w.WriteHeader(500)
+			return
+		}
+		create.Context = nil
+		items = append(items, create)
+	}
+	writeActivityJSON(w, activitypub.OrderedCollection{
+		Context:      activitypub.Context,
+		ID:           cfg.actorURL(user.ID) + "/outbox",
+		Type:         "OrderedCollection",
+		TotalItems:   total,
+		OrderedItems: items,
+	})
+}
+
+// getFollowersCollection only gives the count; who follows a user isn't
+// published.
+func (cfg *apiConfig) getFollowersCollection(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.federatedUser(w, r)
+	if !ok {
+		return
+	}
+	total, err := cfg.db.CountRemoteFollowers(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("error counting followers: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	writeActivityJSON(w, activitypub.OrderedCollection{
+		Context:    activitypub.Context,
+		ID:         cfg.actorURL(user.ID) + "/followers",
+		Type:       "OrderedCollection",
+		TotalItems: total,
+	})
+}
+
+// getNote serves a public or unlisted chirp as a Note.
+func (cfg *apiConfig) getNote(w http.ResponseWriter, r *http.Request) {
+	if !cfg.federating(w) {
+		return
+	}
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		w.WriteHeader(404)
+		return
+	}
+	chirp, err := cfg.db.GetChirp(r.Context(), chirpID)
+	if err != nil {
+		w.WriteHeader(404)
+		return
+	}
+	filter, err := cfg.loadChirpFilter(r.Context(), uuid.Nil, false)
+	if err != nil {
+		log.Printf("error loading chirp filter: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	if !filter.canSee(chirp) {
+		w.WriteHeader(404)
+		return
+	}
+	resp, err := cfg.chirpResponses(r.Context(), uuid.Nil, []database.Chirp{chirp})
+	if err != nil {
+		log.Printf("error building chirp response: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	note := cfg.chirpNote(resp[0])
+	note.Context = activitypub.Context
+	writeActivityJSON(w, note)
+}
+
+// chirpNote renders a chirp as a Note. Public chirps go to everyone,
+// unlisted ones to followers with the public collection copied in, and
+// followers-only chirps to followers alone.
+func (cfg *apiConfig) chirpNote(chirp chirpResponse) activitypub.Note {
+	actor := cfg.actorURL(chirp.UserID)
+	followers := actor + "/followers"
+	note := activitypub.Note{
+		ID:           cfg.noteURL(chirp.ID),
+		Type:         "Note",
+		AttributedTo: actor,
+		URL:          chirpPageURL(cfg.publicURL, chirp.ID),
+		Content:      "<p>" + strings.ReplaceAll(html.EscapeString(chirp.Body), "\n", "<br>") + "</p>",
+		Summary:      chirp.ContentWarning,
+		Sensitive:    chirp.Sensitive || chirp.ContentWarning != "",
+		Published:    chirp.CreatedAt.UTC(),
+		To:           []string{followers},
+		Cc:           []string{},
+	}
+	switch chirp.Visibility {
+	case visibilityPublic:
+		note.To = []string{activitypub.Public}
+		note.Cc = []string{followers}
+	case visibilityUnlisted:
+		note.Cc = []string{activitypub.Public}
+	}
+	for _, m := range chirp.Media {
+		mediaURL := m.URL
+		if strings.HasPrefix(mediaURL, "/") {
+			mediaURL = cfg.publicURL + mediaURL
+		}
+		note.Attachment = append(note.Attachment, activitypub.Document{
+			Type:      "Document",
+			MediaType: m.ContentType,
+			URL:       mediaURL,
+			Blurhash:  m.Blurhash,
+			Width:     int(m.Width),
+			Height:    int(m.Height),
+		})
+	}
+	return note
+}
+
+// federateChirp delivers a new chirp to the author's remote followers.
+// Private chirps stay home.
+func (cfg *apiConfig) federateChirp(ctx context.Context, chirp database.Chirp) {
+	if cfg.publicURL == "" || chirp.Visibility == visibilityPrivate {
+		return
+	}
+	resp, err := cfg.chirpResponses(ctx, uuid.Nil, []database.Chirp{chirp})
+	if err != nil {
+		log.Printf("error building chirp %v for federation: %v", chirp.ID, err)
+		return
+	}
+	note := cfg.chirpNote(resp[0])
+	create, err := activitypub.NewActivity(note.ID+"/activity", "Create", note.AttributedTo, note, note.To, note.Cc)
+	if err != nil {
+		log.Printf("error building activity for chirp %v: %v", chirp.ID, err)
+		return
+	}
+	cfg.deliverToFollowers(ctx, chirp.UserID, create)
+}
+
+// federateDelete tells remote followers a chirp is gone.
+func (cfg *apiConfig) federateDelete(ctx context.Context, chirp database.Chirp) {
+	if cfg.publicURL == "" || chirp.Visibility == visibilityPrivate {
+		return
+	}
+	actor := cfg.actorURL(chirp.UserID)
+	tombstone := map[string]string{"id": cfg.noteURL(chirp.ID), "type": "Tombstone"}
+	del, err := activitypub.NewActivity(cfg.noteURL(chirp.ID)+"#delete", "Delete", actor, tombstone, []string{activitypub.Public}, nil)
+	if err != nil {
+		log.Printf("error building delete for chirp %v: %v", chirp.ID, err)
+		return
+	}
+	cfg.deliverToFollowers(ctx, chirp.UserID, del)
+}
+
+func (cfg *apiConfig) deliverToFollowers(ctx context.Context, userID uuid.UUID, activity activitypub.Activity) {
+	inboxes, err := cfg.db.GetFollowerInboxes(ctx, userID)
+	if err != nil {
+		log.Printf("error getting follower inboxes for %v: %v", userID, err)
+		return
+	}
+	for _, inbox := range inboxes {
+		if err := cfg.queueDelivery(ctx, userID, inbox, activity); err != nil {
+			log.Printf("error queueing delivery to %s: %v", inbox, err)
+		}
+	}
+}
+
+// verifyInbox checks the HTTP signature on an inbox POST against the
+// signer's key from the remote actor cache and returns the actor that
+// signed it.
+func (cfg *apiConfig) verifyInbox(r *http.Request, body []byte) (string, error) {
+	return activitypub.VerifyRequest(r, body, func(ctx context.Context, actorID string, refresh bool) (activitypub.PublicKey, error) {
+		actor, err := cfg.remoteActor(ctx, actorID, refresh)
+		if err != nil {
+			return activitypub.PublicKey{}, err
+		}
+		return activitypub.PublicKey{
+			ID:           actor.PublicKeyID,
+			Owner:        actor.ID,
+			PublicKeyPem: actor.PublicKeyPem,
+		}, nil
+	})
+}
+
+// inbox receives activities for a local user. Follow, Undo, Like and
+// Accept are acted on; anything else is acknowledged and dropped.
+func (cfg *apiConfig) inbox(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.federatedUser(w, r)
+	if !ok {
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxInboxBytes+1))
+	if err != nil || len(body) > maxInboxBytes {
+		w.WriteHeader(400)
+		return
+	}
+	activity := activitypub.Activity{}
+	if err := json.Unmarshal(body, &activity); err != nil {
+		log.Printf("error decoding activity: %v", err)
+		w.WriteHeader(400)
+		return
+	}
+
+	signer, err := cfg.verifyInbox(r, body)
+	if err != nil {
+		log.Printf("rejected inbox delivery: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+	if signer != activity.Actor {
+		w.WriteHeader(401)
+		return
+	}
+
+	object, err := activitypub.ParseObject(activity.Object)
+	if err != nil {
+		w.WriteHeader(400)
+		return
+	}
+
+	switch activity.Type {
+	case "Follow":
+		err = cfg.acceptFollow(r.Context(), user, activity, object)
+	case "Undo":
+		err = cfg.undoActivity(r.Context(), activity.Actor, object)
+	case "Like":
+		err = cfg.receiveLike(r.Context(), activity, object)
+	case "Accept":
+		if followID, ok := cfg.localID(object.ID, "/ap/follows/"); ok {
+			err = cfg.db.AcceptRemoteFollow(r.Context(), database.AcceptRemoteFollowParams{
+				ID:         followID,
+				ActorID:    activity.Actor,
+				AcceptedAt: sql.NullTime{Time: time.Now(), Valid: true},
+			})
+		}
+	}
+	if errors.Is(err, errBadActivity) {
+		w.WriteHeader(400)
+		return
+	}
+	if err != nil {
+		log.Printf("error handling %s activity: %v", activity.Type, err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(202)
+}
+
+var errBadActivity = errors.New("bad activity")
+
+// acceptFollow records a remote follower and sends back an Accept.
+func (cfg *apiConfig) acceptFollow(ctx context.Context, user database.User, activity activitypub.Activity, object activitypub.ObjectRef) error {
+	actor := cfg.actorURL(user.ID)
+	if object.ID != actor || activity.ID == "" {
+		return errBadActivity
+	}
+	remote, err := cfg.remoteActor(ctx, activity.Actor, false)
+	if err != nil {
+		return err
+	}
+	err = cfg.db.CreateRemoteFollower(ctx, database.CreateRemoteFollowerParams{
+		UserID:     user.ID,
+		ActorID:    remote.ID,
+		ActivityID: activity.ID,
+		CreatedAt:  time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	activity.Context = nil
+	accept, err := activitypub.NewActivity(actor+"#accepts/"+uuid.NewString(), "Accept", actor, activity, []string{remote.ID}, nil)
+	if err != nil {
+		return err
+	}
+	if err := cfg.queueDelivery(ctx, user.ID, remote.InboxUrl, accept); err != nil {
+		return err
+	}
+	cfg.notify(ctx, user.ID, notificationRemoteFollow, uuid.NullUUID{})
+	cfg.emitWebhook(ctx, user.ID, webhookFollowerNew, map[string]any{"actor": remote.ID})
+	return nil
+}
+
+// undoActivity reverses an earlier Follow or Like by the same actor.
+func (cfg *apiConfig) undoActivity(ctx context.Context, actorID string, object activitypub.ObjectRef) error {
+	if object.ID == "" {
+		return errBadActivity
+	}
+	err := cfg.db.DeleteRemoteFollowerByActivity(ctx, database.DeleteRemoteFollowerByActivityParams{
+		ActorID:    actorID,
+		ActivityID: object.ID,
+	})
+	if err != nil {
+		return err
+	}
+	return cfg.db.DeleteRemoteLikeByActivity(ctx, database.DeleteRemoteLikeByActivityParams{
+		ActorID:    actorID,
+		ActivityID: object.ID,
+	})
+}
+
+// receiveLike records a remote like on a chirp anyone may see and lets
+// the author know.
+func (cfg *apiConfig) receiveLike(ctx context.Context, activity activitypub.Activity, object activitypub.ObjectRef) error {
+	chirpID, ok := cfg.localID(object.ID, "/ap/chirps/")
+	if !ok || activity.ID == "" {
+		return errBadActivity
+	}
+	chirp, err := cfg.db.GetChirp(ctx, chirpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errBadActivity
+	}
+	if err != nil {
+		return err
+	}
+	filter, err := cfg.loadChirpFilter(ctx, uuid.Nil, false)
+	if err != nil {
+		return err
+	}
+	if !filter.canSee(chirp) {
+		return errBadActivity
+	}
+	if _, err := cfg.remoteActor(ctx, activity.Actor, false); err != nil {
+		return err
+	}
+
+	inserted, err := cfg.db.CreateRemoteLike(ctx, database.CreateRemoteLikeParams{
+		ChirpID:    chirp.ID,
+		ActorID:    activity.Actor,
+		ActivityID: activity.ID,
+		CreatedAt:  time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	if inserted > 0 {
+		cfg.notify(ctx, chirp.UserID, notificationRemoteLike, uuid.NullUUID{UUID: chirp.ID, Valid: true})
+		cfg.recordEngagement(chirp, activity.Actor)
+	}
+	return nil
+}
+
+// queueDelivery stores an activity for the delivery worker to sign and
+// send.
+func (cfg *apiConfig) queueDelivery(ctx context.Context, senderID uuid.UUID, inbox string, activity activitypub.Activity) error {
+	if activity.Context == nil {
+		activity.Context = activitypub.Context
+	}
+	payload, err := json.Marshal(activity)
+	if err != nil {
+		return fmt.Errorf("marshalling activity: %w", err)
+	}
+	now := time.Now()
+	return cfg.db.CreateDelivery(ctx, database.CreateDeliveryParams{
+		ID:            uuid.New(),
+		CreatedAt:     now,
+		SenderID:      senderID,
+		InboxUrl:      inbox,
+		Payload:       string(payload),
+		NextAttemptAt: now,
+	})
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tristenkelly/chirpy/internal/database"
+)
+
+// Kinds of analytics event. Each viewer counts once per kind, subject and
+// day.
+const (
+	analyticsImpression   = "impression"
+	analyticsEngagement   = "engagement"
+	analyticsProfileVisit = "profile_visit"
+)
+
+const (
+	analyticsFlushInterval = 30 * time.Second
+	analyticsFlushBatch    = 5000
+	maxAnalyticsBuffer     = 100000
+	analyticsDay           = "2006-01-02"
+)
+
+type analyticsEvent struct {
+	kind    string
+	subject uuid.UUID
+	viewer  string
+	day     string
+}
+
+// analyticsBuffer holds events between flushes. Repeats are dropped here
+// within one instance; the database drops repeats across instances and
+// flushes.
+type analyticsBuffer struct {
+	mu     sync.Mutex
+	events map[analyticsEvent]struct{}
+}
+
+func (b *analyticsBuffer) add(e analyticsEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.events == nil {
+		b.events = map[analyticsEvent]struct{}{}
+	}
+	// A full buffer means flushing has fallen behind; counts are allowed
+	// to come up short rather than use unbounded memory.
+	if len(b.events) >= maxAnalyticsBuffer {
+		return
+	}
+	b.events[e] = struct{}{}
+}
+
+func (b *analyticsBuffer) take() []analyticsEvent {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	events := make([]analyticsEvent, 0, len(b.events))
+	for e := range b.events {
+		events = append(events, e)
+	}
+	b.events = nil
+	return events
+}
+
+// analyticsViewer names a viewer for deduplication: their user ID when
+// logged in, otherwise a keyed hash of their address so the address itself
+// isn't stored.
+func (cfg *apiConfig) analyticsViewer(r *http.Request, viewer uuid.UUID, loggedIn bool) string {
+	if loggedIn {
+		return viewer.String()
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	mac := hmac.New(sha256.New, []byte(cfg.secret))
+	mac.Write([]byte(host))
+	return "anon:" + hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+func (cfg *apiConfig) recordAnalytics(kind string, subject uuid.UUID, viewer string) {
+	cfg.analytics.add(analyticsEvent{
+		kind:    kind,
+		subject: subject,
+		viewer:  viewer,
+		day:     time.Now().UTC().Format(analyticsDay),
+	})
+}
+
+// recordImpressions counts a view of each chirp served, leaving out the
+// viewer's own.
+func (cfg *apiConfig) recordImpressions(r *http.Request, viewer uuid.UUID, loggedIn bool, chirps []chirpResponse) {
+	key := cfg.analyticsViewer(r, viewer, loggedIn)
+	for _, chirp := range chirps {
+		if loggedIn && chirp.UserID == viewer {
+			continue
+		}
+		cfg.recordAnalytics(analyticsImpression, chirp.ID, key)
+	}
+}
+
+// recordEngagement counts an interaction with a chirp: a bookmark, a poll
+// vote or a like from another server. viewer is a user ID or a remote actor.
+func (cfg *apiConfig) recordEngagement(chirp database.Chirp, viewer string) {
+	if viewer == chirp.UserID.String() {
+		return
+	}
+	cfg.recordAnalytics(analyticsEngagement, chirp.ID, viewer)
+}
+
+func (cfg *apiConfig) recordProfileVisit(r *http.Request, viewer uuid.UUID, loggedIn bool, userID uuid.UUID) {
+	if loggedIn && viewer == userID {
+		return
+	}
+	cfg.recordAnalytics(analyticsProfileVisit, userID, cfg.analyticsViewer(r, viewer, loggedIn))
+}
+
+// runAnalyticsFlusher writes buffered events to the daily stats tables.
+// main flushes whatever is left once the server has shut down.
+func (cfg *apiConfig) runAnalyticsFlusher(ctx context.Context) {
+	ticker := time.NewTicker(analyticsFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		cfg.flushAnalytics(ctx)
+	}
+}
+
+func (cfg *apiConfig) flushAnalytics(ctx context.Context) {
+	events := cfg.analytics.take()
+	for start := 0; start < len(events); start += analyticsFlushBatch {
+		batch := events[start:min(start+analyticsFlushBatch, len(events))]
+		params := database.RecordAnalyticsEventsParams{
+			Kinds:      make([]string, len(batch)),
+			SubjectIds: make([]uuid.UUID, len(batch)),
+			Viewers:    make([]string, len(batch)),
+			Days:       make([]string, len(batch)),
+		}
+		for i, e := range batch {
+			params.Kinds[i] = e.kind
+			params.SubjectIds[i] = e.subject
+			params.Viewers[i] = e.viewer
+			params.Days[i] = e.day
+		}
+		if err := cfg.db.RecordAnalyticsEvents(ctx, params); err != nil {
+			// The statement is all or nothing, so the batch can go back
+			// for the next flush without being counted twice.
+			log.Printf("error recording analytics: %v", err)
+			for _, e := range batch {
+				cfg.analytics.add(e)
+			}
+		}
+	}
+
+	// Dedup rows are only needed until the day is over; yesterday's are
+	// kept for events buffered just before midnight.
+	yesterday := time.Now().UTC().AddDate(0, 0, -1)
+	if err := cfg.db.DeleteOldAnalyticsSeen(ctx, yesterday); err != nil {
+		log.Printf("error pruning analytics: %v", err)
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/tristenkelly/chirpy/internal/auth"
+	"github.com/tristenkelly/chirpy/internal/database"
+)
+
+// Chirp visibility levels. Unlisted chirps can be opened by anyone with the
+// ID but stay out of listings.
+const (
+	visibilityPublic    = "public"
+	visibilityUnlisted  = "unlisted"
+	visibilityFollowers = "followers"
+	visibilityPrivate   = "private"
+)
+
+var visibilities = map[string]bool{
+	visibilityPublic:    true,
+	visibilityUnlisted:  true,
+	visibilityFollowers: true,
+	visibilityPrivate:   true,
+}
+
+// viewerID returns the user behind an optional bearer token. Public endpoints
+// use it to personalise results without requiring a login.
+func (cfg *apiConfig) viewerID(r *http.Request) (uuid.UUID, bool) {
+	if r.Header.Get("Authorization") == "" {
+		return uuid.UUID{}, false
+	}
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.UUID{}, false
+	}
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		return uuid.UUID{}, false
+	}
+	return userID, true
+}
+
+// chirpFilter hides chirps a viewer shouldn't see in listings: chirps hidden
+// by a moderator, authors on either side of a block, authors the viewer
+// muted, and chirps containing one of the viewer's muted words or hashtags.
+type chirpFilter struct {
+	viewer        uuid.UUID
+	loggedIn      bool
+	isModerator   bool
+	hiddenAuthors map[uuid.UUID]bool
+	blocked       map[uuid.UUID]bool
+	following     map[uuid.UUID]bool
+	mutedWords    []string
+}
+
+func (cfg *apiConfig) loadChirpFilter(ctx context.Context, viewer uuid.UUID, loggedIn bool) (chirpFilter, error) {
+	filter := chirpFilter{
+		viewer:        viewer,
+		loggedIn:      loggedIn,
+		hiddenAuthors: map[uuid.UUID]bool{},
+		blocked:       map[uuid.UUID]bool{},
+		following:     map[uuid.UUID]bool{},
+	}
+	if !loggedIn {
+		return filter, nil
+	}
+
+	user, err := cfg.db.GetUserByID(ctx, viewer)
+	if err != nil {
+		return filter, err
+	}
+	filter.isModerator = user.IsModerator
+
+	blocked, err := cfg.db.GetBlockRelations(ctx, viewer)
+	if err != nil {
+		return filter, err
+	}
+	for _, id := range blocked {
+		filter.hiddenAuthors[id] = true
+		filter.blocked[id] = true
+	}
+
+	following, err := cfg.db.GetFollowing(ctx, viewer)
+	if err != nil {
+		return filter, err
+	}
+	for _, f := range following {
+		filter.following[f.FolloweeID] = true
+	}
+
+	muted, err := cfg.db.GetMutedUsers(ctx, viewer)
+	if err != nil {
+		return filter, err
+	}
+	for _, m := range muted {
+		filter.hiddenAuthors[m.MutedID] = true
+	}
+
+	words, err := cfg.db.GetActiveMutedWords(ctx, database.GetActiveMutedWordsParams{
+		UserID:    viewer,
+		ExpiresAt: sql.NullTime{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		return filter, err
+	}
+	for _, w := range words {
+		filter.mutedWords = append(filter.mutedWords, strings.ToLower(w.Word))
+	}
+	return filter, nil
+}
+
+// canSee reports whether the viewer may open the chirp at all. Authors
+// always see their own chirps. Moderator hidden chirps stay visible to
+// moderators; followers-only chirps need the viewer to follow the author,
+// and private chirps are for the author alone.
+func (f chirpFilter) canSee(chirp database.Chirp) bool {
+	isAuthor := f.loggedIn && f.viewer == chirp.UserID
+	if isAuthor {
+		return true
+	}
+	if chirp.HiddenAt.Valid && !f.isModerator {
+		return false
+	}
+	switch chirp.Visibility {
+	case visibilityFollowers:
+		return f.following[chirp.UserID]
+	case visibilityPrivate:
+		return false
+	default:
+		return true
+	}
+}
+
+// canOpen is canSee plus the block check visibleChirp makes: neither the
+// viewer nor the author may have blocked the other.
+func (f chirpFilter) canOpen(chirp database.Chirp) bool {
+	return f.canSee(chirp) && !f.blocked[chirp.UserID]
+}
+
+// allows reports whether the chirp belongs in the viewer's listings.
+func (f chirpFilter) allows(chirp database.Chirp) bool {
+	if !f.canSee(chirp) {
+		return false
+	}
+	if chirp.Visibility == visibilityUnlisted && !(f.loggedIn && f.viewer == chirp.UserID) {
+		return false
+	}
+	if f.hiddenAuthors[chirp.UserID] {
+		return false
+	}
+	if len(f.mutedWords) == 0 {
+		return true
+	}
+	body := strings.ToLower(chirp.Body)
+	tokens := strings.FieldsFunc(body, func(r rune) bool {
+		return r != '#' && !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	for _, word := range f.mutedWords {
+		if strings.Contains(word, " ") {
+			if strings.Contains(body, word) {
+				return false
+			}
+			continue
+		}
+		for _, token := range tokens {
+			// Muting a word also mutes its hashtag, but muting a hashtag
+			// leaves the plain word alone.
+			if token == word || (!strings.HasPrefix(word, "#") && token == "#"+word) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isBlocked reports whether either user has blocked the other.
+func (cfg *apiConfig) isBlocked(ctx context.Context, a, b uuid.UUID) (bool, error) {
+	return cfg.db.IsBlockedEitherWay(ctx, database.IsBlockedEitherWayParams{
+		BlockerID: a,
+		BlockedID: b,
+	})
+}
+
+// visibleChirp loads a chirp for a logged-in viewer. The chirp must pass
+// canSee and neither user may have blocked the other; ok is false when the
+// chirp doesn't exist or is off limits.
+func (cfg *apiConfig) visibleChirp(ctx context.Context, viewer uuid.UUID, chirpID uuid.UUID) (database.Chirp, bool, error) {
+	chirp, err := cfg.db.GetChirp(ctx, chirpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.Chirp{}, false, nil
+	}
+	if err != nil {
+		return database.Chirp{}, false, err
+	}
+	filter, err := cfg.loadChirpFilter(ctx, viewer, true)
+	if err != nil {
+		return database.Chirp{}, false, err
+	}
+	if !filter.canSee(chirp) {
+		return database.Chirp{}, false, nil
+	}
+	blocked, err := cfg.isBlocked(ctx, viewer, chirp.UserID)
+	if err != nil {
+		return database.Chirp{}, false, err
+	}
+	return chirp, !blocked, nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/tristenkelly/chirpy/internal/database"
+	"github.com/tristenkelly/chirpy/internal/unfurl"
+)
+
+type mediaResponse struct {
+	ID          uuid.UUID `json:"id"`
+	URL         string    `json:"url"`
+	ContentType string    `json:"content_type"`
+	SizeBytes   int64     `json:"size_bytes"`
+	// The rest is filled in once the media worker has processed the
+	// upload.
+	Width    int32                           `json:"width,omitempty"`
+	Height   int32                           `json:"height,omitempty"`
+	Blurhash string                          `json:"blurhash,omitempty"`
+	Variants map[string]mediaVariantResponse `json:"variants,omitempty"`
+}
+
+type mediaVariantResponse struct {
+	URL         string `json:"url"`
+	ContentType string `json:"content_type"`
+	Width       int32  `json:"width"`
+	Height      int32  `json:"height"`
+}
+
+// chirpPage is a page of chirps from a cursor-paginated listing.
+type chirpPage struct {
+	Chirps     []chirpResponse `json:"chirps"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+// chirpResponses turns chirps into API responses as seen by viewer
+// (uuid.Nil when logged out), loading everything attached to them in one
+// query per kind of attachment rather than one per chirp.
+func (cfg *apiConfig) chirpResponses(ctx context.Context, viewer uuid.UUID, chirps []database.Chirp) ([]chirpResponse, error) {
+	if len(chirps) == 0 {
+		return nil, nil
+	}
+	ids := make([]uuid.UUID, 0, len(chirps))
+	for _, chirp := range chirps {
+		ids = append(ids, chirp.ID)
+	}
+
+	media, err := cfg.db.GetMediaForChirps(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	mediaIDs := make([]uuid.UUID, 0, len(media))
+	for _, m := range media {
+		mediaIDs = append(mediaIDs, m.ID)
+	}
+	variants, err := cfg.db.GetVariantsForMedia(ctx, mediaIDs)
+	if err != nil {
+		return nil, err
+	}
+	variantsByMedia := map[uuid.UUID][]database.MediaVariant{}
+	for _, v := range variants {
+		variantsByMedia[v.MediaID] = append(variantsByMedia[v.MediaID], v)
+	}
+	mediaByChirp := map[uuid.UUID][]mediaResponse{}
+	for _, m := range media {
+		mediaByChirp[m.ChirpID.UUID] = append(mediaByChirp[m.ChirpID.UUID], mediaToResponse(cfg, m, variantsByMedia[m.ID]))
+	}
+
+	polls, err := cfg.pollResponses(ctx, viewer, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	bookmarked := map[uuid.UUID]bool{}
+	if viewer != uuid.Nil {
+		bookmarks, err := cfg.db.GetBookmarkedChirpIDs(ctx, database.GetBookmarkedChirpIDsParams{
+			UserID:   viewer,
+			ChirpIds: ids,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range bookmarks {
+			bookmarked[id] = true
+		}
+	}
+
+	expand := false
+	if viewer != uuid.Nil {
+		user, err := cfg.db.GetUserByID(ctx, viewer)
+		if err != nil {
+			return nil, err
+		}
+		expand = user.ExpandSensitive
+	}
+
+	previews, err := cfg.linkPreviews(ctx, chirps)
+	if err != nil {
+		return nil, err
+	}
+
+	pins, err := cfg.db.GetPinnedChirpIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	pinned := map[uuid.UUID]bool{}
+	for _, id := range pins {
+		pinned[id] = true
+	}
+
+	var resp []chirpResponse
+	for _, chirp := range chirps {
+		attachments := mediaByChirp[chirp.ID]
+		if attachments == nil {
+			attachments = []mediaResponse{}
+		}
+		resp = append(resp, chirpResponse{
+			ID:             chirp.ID,
+			CreatedAt:      chirp.CreatedAt,
+			UpdatedAt:      chirp.UpdatedAt,
+			Body:           chirp.Body,
+			UserID:         chirp.UserID,
+			Media:          attachments,
+			Poll:           polls[chirp.ID],
+			Visibility:     chirp.Visibility,
+			Bookmarked:     bookmarked[chirp.ID],
+			Pinned:         pinned[chirp.ID],
+			ContentWarning: chirp.ContentWarning,
+			Sensitive:      chirp.Sensitive,
+			Collapsed:      (chirp.Sensitive || chirp.ContentWarning != "") && !expand,
+			LinkPreview:    previews[unfurl.FirstURL(chirp.Body)],
+		})
+	}
+	return resp, nil
+}
+
+func mediaToResponse(cfg *apiConfig, m database.MediaFile, variants []database.MediaVariant) mediaResponse {
+	resp := mediaResponse{
+		ID:          m.ID,
+		URL:         cfg.media.URL(m.StorageKey),
+		ContentType: m.ContentType,
+		SizeBytes:   m.SizeBytes,
+		Width:       m.Width.Int32,
+		Height:      m.Height.Int32,
+		Blurhash:    m.Blurhash.String,
+	}
+	if len(variants) > 0 {
+		resp.Variants = map[string]mediaVariantResponse{}
+		for _, v := range variants {
+			resp.Variants[v.Name] = mediaVariantResponse{
+				URL:         cfg.media.URL(v.StorageKey),
+				ContentType: v.ContentType,
+				Width:       v.Width,
+				Height:      v.Height,
+			}
+		}
+	}
+	return resp
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/tristenkelly/chirpy/internal/auth"
+	"github.com/tristenkelly/chirpy/internal/database"
+	"github.com/tristenkelly/chirpy/internal/entitlements"
+)
+
+func userTier(user database.User) entitlements.Tier {
+	if user.IsChirpyRed {
+		return entitlements.Red
+	}
+	return entitlements.Free
+}
+
+// limitsFor returns what the user's tier allows. Handlers check tier
+// limits through this or requireFeature rather than looking at
+// is_chirpy_red themselves.
+func (cfg *apiConfig) limitsFor(user database.User) entitlements.Limits {
+	return cfg.tiers.For(userTier(user))
+}
+
+// requireFeature reports whether the user's tier includes feature, writing
+// a 403 when it doesn't.
+func (cfg *apiConfig) requireFeature(w http.ResponseWriter, user database.User, feature entitlements.Feature) bool {
+	if cfg.limitsFor(user).Allows(feature) {
+		return true
+	}
+	type errorResponse struct {
+		Error   string `json:"error"`
+		Feature string `json:"feature"`
+	}
+	val, _ := json.Marshal(errorResponse{
+		Error:   "Your plan doesn't include this feature",
+		Feature: string(feature),
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(403)
+	w.Write(val)
+	return false
+}
+
+// getEntitlements tells clients what the caller's tier allows, so they can
+// hide what isn't available.
+func (cfg *apiConfig) getEntitlements(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting token: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		log.Printf("token not valid: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		log.Printf("error getting user: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	type entitlementsResponse struct {
+		Tier   entitlements.Tier   `json:"tier"`
+		Limits entitlements.Limits `json:"limits"`
+	}
+	val, err := json.Marshal(entitlementsResponse{
+		Tier:   userTier(user),
+		Limits: cfg.limitsFor(user),
+	})
+	if err != nil {
+		log.Printf("error marshalling entitlements: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(val)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tristenkelly/chirpy/internal/activitypub"
+	"github.com/tristenkelly/chirpy/internal/database"
+)
+
+const (
+	deliveryInterval    = 10 * time.Second
+	deliveryBatchSize   = 20
+	maxDeliveryAttempts = 8
+	// deliveryLease is how long a claimed delivery is left to the worker
+	// that claimed it. It covers a full batch of requests timing out.
+	deliveryLease = 5 * time.Minute
+)
+
+// runDeliveryWorker sends queued activities to remote inboxes, retrying
+// failures with exponential backoff.
+func (cfg *apiConfig) runDeliveryWorker(ctx context.Context) {
+	if cfg.publicURL == "" {
+		return
+	}
+	ticker := time.NewTicker(deliveryInterval)
+	defer ticker.Stop()
+	for {
+		// A full batch means there may be more due right away.
+		for cfg.sendDueDeliveries(ctx) == deliveryBatchSize {
+			if ctx.Err() != nil {
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sendDueDeliveries sends one batch of due deliveries and returns how many
+// it claimed. Claiming pushes next_attempt_at out by deliveryLease, so with
+// several replicas running each delivery is attempted by one of them at a
+// time, and no row locks are held while requests are in flight.
+func (cfg *apiConfig) sendDueDeliveries(ctx context.Context) int {
+	due, err := cfg.claimDueDeliveries(ctx)
+	if err != nil {
+		log.Printf("error claiming deliveries: %v", err)
+		return 0
+	}
+
+	// Results are still recorded once shutdown has begun, so an activity
+	// that went out isn't sent again when its lease runs out.
+	recordCtx := context.WithoutCancel(ctx)
+	for _, d := range due {
+		err := cfg.deliver(ctx, d)
+		if ctx.Err() != nil {
+			// Cut off by shutdown. This and the rest of the batch are
+			// retried when the lease expires, without counting an attempt.
+			break
+		}
+		if err == nil || d.Attempts+1 >= maxDeliveryAttempts {
+			if err != nil {
+				log.Printf("giving up on delivery %v to %s: %v", d.ID, d.InboxUrl, err)
+			}
+			err = cfg.db.DeleteDelivery(recordCtx, d.ID)
+		} else {
+			log.Printf("error delivering %v to %s: %v", d.ID, d.InboxUrl, err)
+			err = cfg.db.RetryDelivery(recordCtx, database.RetryDeliveryParams{
+				ID:            d.ID,
+				NextAttemptAt: time.Now().Add(time.Minute << d.Attempts),
+			})
+		}
+		if err != nil {
+			log.Printf("error updating delivery %v: %v", d.ID, err)
+		}
+	}
+	return len(due)
+}
+
+// claimDueDeliveries leases a batch of due deliveries to this worker.
+func (cfg *apiConfig) claimDueDeliveries(ctx context.Context) ([]database.FederationDelivery, error) {
+	tx, err := cfg.sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	q := cfg.withTx(tx)
+
+	now := time.Now()
+	due, err := q.ClaimDueDeliveries(ctx, database.ClaimDueDeliveriesParams{
+		NextAttemptAt: now,
+		Limit:         deliveryBatchSize,
+	})
+	if err != nil || len(due) == 0 {
+		return nil, err
+	}
+	ids := make([]uuid.UUID, len(due))
+	for i, d := range due {
+		ids[i] = d.ID
+	}
+	err = q.LeaseDeliveries(ctx, database.LeaseDeliveriesParams{
+		NextAttemptAt: now.Add(deliveryLease),
+		Ids:           ids,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return due, nil
+}
+
+func (cfg *apiConfig) deliver(ctx context.Context, d database.FederationDelivery) error {
+	key, err := cfg.actorKey(ctx, d.SenderID)
+	if err != nil {
+		return err
+	}
+	privateKey, err := activitypub.ParsePrivateKey(key.PrivateKeyPem)
+	if err != nil {
+		return err
+	}
+	return cfg.apClient.Deliver(ctx, d.InboxUrl, []byte(d.Payload), cfg.keyID(d.SenderID), privateKey)
+}
+
+func (cfg *apiConfig) keyID(userID uuid.UUID) string {
+	return cfg.actorURL(userID) + "#main-key"
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tristenkelly/chirpy/internal/auth"
+	"github.com/tristenkelly/chirpy/internal/database"
+	"github.com/tristenkelly/chirpy/internal/entitlements"
+)
+
+const (
+	defaultAnalyticsDays = 30
+	maxAnalyticsDays     = 90
+)
+
+type analyticsCounts struct {
+	Impressions int64 `json:"impressions"`
+	Engagements int64 `json:"engagements"`
+}
+
+type chirpAnalyticsDay struct {
+	Date string `json:"date"`
+	analyticsCounts
+}
+
+type userAnalyticsCounts struct {
+	analyticsCounts
+	ProfileVisits int64 `json:"profile_visits"`
+}
+
+type userAnalyticsDay struct {
+	Date string `json:"date"`
+	userAnalyticsCounts
+}
+
+// analyticsUser authenticates the caller and checks their tier includes
+// analytics, writing the error status itself.
+func (cfg *apiConfig) analyticsUser(w http.ResponseWriter, r *http.Request) (database.User, bool) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting token: %v", err)
+		w.WriteHeader(401)
+		return database.User{}, false
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		log.Printf("token not valid: %v", err)
+		w.WriteHeader(401)
+		return database.User{}, false
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		log.Printf("error getting user: %v", err)
+		w.WriteHeader(401)
+		return database.User{}, false
+	}
+	if !cfg.requireFeature(w, user, entitlements.Analytics) {
+		return database.User{}, false
+	}
+	return user, true
+}
+
+// analyticsDays reads the days parameter, returning the UTC dates of the
+// range oldest first.
+func analyticsDays(r *http.Request) ([]string, bool) {
+	days := defaultAnalyticsDays
+	if s := r.URL.Query().Get("days"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxAnalyticsDays {
+			return nil, false
+		}
+		days = n
+	}
+	today := time.Now().UTC()
+	dates := make([]string, days)
+	for i := range dates {
+		dates[i] = today.AddDate(0, 0, i-days+1).Format(analyticsDay)
+	}
+	return dates, true
+}
+
+func parseAnalyticsDay(date string) time.Time {
+	t, _ := time.Parse(analyticsDay, date)
+	return t
+}
+
+// getChirpAnalytics returns daily impressions and engagements for one of
+// the caller's chirps. Counts lag by up to the flush interval.
+func (cfg *apiConfig) getChirpAnalytics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	user, ok := cfg.analyticsUser(w, r)
+	if !ok {
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		w.WriteHeader(404)
+		return
+	}
+	chirp, err := cfg.db.GetChirp(r.Context(), chirpID)
+	if err != nil {
+		w.WriteHeader(404)
+		return
+	}
+	if chirp.UserID != user.ID {
+		w.WriteHeader(403)
+		return
+	}
+	dates, ok := analyticsDays(r)
+	if !ok {
+		w.WriteHeader(400)
+		return
+	}
+
+	rows, err := cfg.db.GetChirpStats(r.Context(), database.GetChirpStatsParams{
+		ChirpID: chirp.ID,
+		Day:     parseAnalyticsDay(dates[0]),
+	})
+	if err != nil {
+		log.Printf("error getting chirp stats: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	byDay := map[string]analyticsCounts{}
+	for _, row := range rows {
+		byDay[row.Day.Format(analyticsDay)] = analyticsCounts{
+			Impressions: int64(row.Impressions),
+			Engagements: int64(row.Engagements),
+		}
+	}
+
+	type chirpAnalytics struct {
+		ChirpID uuid.UUID           `json:"chirp_id"`
+		Totals  analyticsCounts     `json:"totals"`
+		Days    []chirpAnalyticsDay `json:"days"`
+	}
+	resp := chirpAnalytics{ChirpID: chirp.ID}
+	for _, date := range dates {
+		counts := byDay[date]
+		resp.Totals.Impressions += counts.Impressions
+		resp.Totals.Engagements += counts.Engagements
+		resp.Days = append(resp.Days, chirpAnalyticsDay{Date: date, analyticsCounts: counts})
+	}
+
+	val, err := json.Marshal(resp)
+	if err != nil {
+		log.Printf("error marshalling chirp analytics: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(val)
+}
+
+// getUserAnalytics returns the caller's daily totals across all their
+// chirps, along with visits to their profile.
+func (cfg *apiConfig) getUserAnalytics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	user, ok := cfg.analyticsUser(w, r)
+	if !ok {
+		return
+	}
+	dates, ok := analyticsDays(r)
+	if !ok {
+		w.WriteHeader(400)
+		return
+	}
+	from := parseAnalyticsDay(dates[0])
+
+	chirpRows, err := cfg.db.GetAuthorStats(r.Context(), database.GetAuthorStatsParams{
+		UserID: user.ID,
+		Day:    from,
+	})
+	if err != nil {
+		log.Printf("error getting author stats: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	visitRows, err := cfg.db.GetProfileVisits(r.Context(), database.GetProfileVisitsParams{
+		UserID: user.ID,
+		Day:    from,
+	})
+	if err != nil {
+		log.Printf("error getting profile visits: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	byDay := map[string]userAnalyticsCounts{}
+	for _, row := range chirpRows {
+		counts := byDay[row.Day.Format(analyticsDay)]
+		counts.Impressions = row.Impressions
+		counts.Engagements = row.Engagements
+		byDay[row.Day.Format(analyticsDay)] = counts
+	}
+	for _, row := range visitRows {
+		counts := byDay[row.Day.Format(analyticsDay)]
+		counts.ProfileVisits = int64(row.ProfileVisits)
+		byDay[row.Day.Format(analyticsDay)] = counts
+	}
+
+	type userAnalytics struct {
+		Totals userAnalyticsCounts `json:"totals"`
+		Days   []userAnalyticsDay  `json:"days"`
+	}
+	resp := userAnalytics{}
+	for _, date := range dates {
+		counts := byDay[date]
+		resp.Totals.Impressions += counts.Impressions
+		resp.Totals.Engagements += counts.Engagements
+		resp.Totals.ProfileVisits += counts.ProfileVisits
+		resp.Days = append(resp.Days, userAnalyticsDay{Date: date, userAnalyticsCounts: counts})
+	}
+
+	val, err := json.Marshal(resp)
+	if err != nil {
+		log.Printf("error marshalling user analytics: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(val)
+}
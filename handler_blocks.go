@@ -0,0 +1,362 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/tristenkelly/chirpy/internal/auth"
+	"github.com/tristenkelly/chirpy/internal/database"
+)
+
+// relationTarget authenticates the caller and resolves the {userID} path
+// value for the block and mute endpoints, writing the error status itself.
+func (cfg *apiConfig) relationTarget(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting token: %v", err)
+		w.WriteHeader(401)
+		return uuid.UUID{}, uuid.UUID{}, false
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		log.Printf("token not valid: %v", err)
+		w.WriteHeader(401)
+		return uuid.UUID{}, uuid.UUID{}, false
+	}
+
+	targetID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		log.Printf("error converting ID to UUID %v", err)
+		w.WriteHeader(404)
+		return uuid.UUID{}, uuid.UUID{}, false
+	}
+	if targetID == userID {
+		w.WriteHeader(400)
+		return uuid.UUID{}, uuid.UUID{}, false
+	}
+	return userID, targetID, true
+}
+
+// isMissingRow reports whether err means a row the query needed isn't
+// there: no rows came back, or a foreign key pointed at nothing.
+func isMissingRow(err error) bool {
+	if errors.Is(err, sql.ErrNoRows) {
+		return true
+	}
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23503"
+}
+
+func (cfg *apiConfig) blockUser(w http.ResponseWriter, r *http.Request) {
+	userID, targetID, ok := cfg.relationTarget(w, r)
+	if !ok {
+		return
+	}
+	err := cfg.db.BlockUser(r.Context(), database.BlockUserParams{
+		BlockerID: userID,
+		BlockedID: targetID,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		if isMissingRow(err) {
+			w.WriteHeader(404)
+			return
+		}
+		log.Printf("error blocking user: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	// A block ends any follow between the two users, so neither keeps
+	// seeing the other's followers-only chirps.
+	err = cfg.db.DeleteFollowsBetween(r.Context(), database.DeleteFollowsBetweenParams{
+		FollowerID: userID,
+		FolloweeID: targetID,
+	})
+	if err != nil {
+		log.Printf("error removing follows: %v", err)
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) unblockUser(w http.ResponseWriter, r *http.Request) {
+	userID, targetID, ok := cfg.relationTarget(w, r)
+	if !ok {
+		return
+	}
+	err := cfg.db.UnblockUser(r.Context(), database.UnblockUserParams{
+		BlockerID: userID,
+		BlockedID: targetID,
+	})
+	if err != nil {
+		log.Printf("error unblocking user: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) muteUser(w http.ResponseWriter, r *http.Request) {
+	userID, targetID, ok := cfg.relationTarget(w, r)
+	if !ok {
+		return
+	}
+	err := cfg.db.MuteUser(r.Context(), database.MuteUserParams{
+		MuterID:   userID,
+		MutedID:   targetID,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		if isMissingRow(err) {
+			w.WriteHeader(404)
+			return
+		}
+		log.Printf("error muting user: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) unmuteUser(w http.ResponseWriter, r *http.Request) {
+	userID, targetID, ok := cfg.relationTarget(w, r)
+	if !ok {
+		return
+	}
+	err := cfg.db.UnmuteUser(r.Context(), database.UnmuteUserParams{
+		MuterID: userID,
+		MutedID: targetID,
+	})
+	if err != nil {
+		log.Printf("error unmuting user: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) getBlocks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting token: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		log.Printf("token not valid: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	blocks, err := cfg.db.GetBlockedUsers(r.Context(), userID)
+	if err != nil {
+		log.Printf("error getting blocked users: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	mutes, err := cfg.db.GetMutedUsers(r.Context(), userID)
+	if err != nil {
+		log.Printf("error getting muted users: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	type relation struct {
+		UserID    uuid.UUID `json:"user_id"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+
+	type relationList struct {
+		Blocked []relation `json:"blocked"`
+		Muted   []relation `json:"muted"`
+	}
+
+	resp := relationList{Blocked: []relation{}, Muted: []relation{}}
+	for _, b := range blocks {
+		resp.Blocked = append(resp.Blocked, relation{UserID: b.BlockedID, CreatedAt: b.CreatedAt})
+	}
+	for _, m := range mutes {
+		resp.Muted = append(resp.Muted, relation{UserID: m.MutedID, CreatedAt: m.CreatedAt})
+	}
+
+	val, err := json.Marshal(resp)
+	if err != nil {
+		log.Printf("error marshalling blocks: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(val)
+}
+
+type mutedWordResponse struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	Word      string     `json:"word"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func mutedWordToResponse(word database.MutedWord) mutedWordResponse {
+	resp := mutedWordResponse{
+		ID:        word.ID,
+		CreatedAt: word.CreatedAt,
+		Word:      word.Word,
+	}
+	if word.ExpiresAt.Valid {
+		expiresAt := word.ExpiresAt.Time
+		resp.ExpiresAt = &expiresAt
+	}
+	return resp
+}
+
+func (cfg *apiConfig) createMutedWord(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting token: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		log.Printf("token not valid: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	type paramaters struct {
+		Word      string     `json:"word"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+
+	params := paramaters{}
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&params)
+	if err != nil {
+		log.Printf("error decoding muted word params: %v", err)
+		w.WriteHeader(400)
+		return
+	}
+
+	word := strings.TrimSpace(params.Word)
+	if word == "" || word == "#" {
+		w.WriteHeader(400)
+		return
+	}
+	expiresAt := sql.NullTime{}
+	if params.ExpiresAt != nil {
+		if params.ExpiresAt.Before(time.Now()) {
+			w.WriteHeader(400)
+			return
+		}
+		expiresAt = sql.NullTime{Time: params.ExpiresAt.Local(), Valid: true}
+	}
+
+	muted, err := cfg.db.CreateMutedWord(r.Context(), database.CreateMutedWordParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UserID:    userID,
+		Word:      strings.ToLower(word),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		log.Printf("error creating muted word: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	val, err := json.Marshal(mutedWordToResponse(muted))
+	if err != nil {
+		log.Printf("error marshalling muted word: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(201)
+	w.Write(val)
+}
+
+func (cfg *apiConfig) getMutedWords(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting token: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		log.Printf("token not valid: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	words, err := cfg.db.GetMutedWords(r.Context(), userID)
+	if err != nil {
+		log.Printf("error getting muted words: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	resp := []mutedWordResponse{}
+	for _, word := range words {
+		resp = append(resp, mutedWordToResponse(word))
+	}
+
+	val, err := json.Marshal(resp)
+	if err != nil {
+		log.Printf("error marshalling muted words: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(val)
+}
+
+func (cfg *apiConfig) deleteMutedWord(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting token: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		log.Printf("token not valid: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	wordID, err := uuid.Parse(r.PathValue("wordID"))
+	if err != nil {
+		log.Printf("error converting ID to UUID %v", err)
+		w.WriteHeader(404)
+		return
+	}
+
+	err = cfg.db.DeleteMutedWord(r.Context(), database.DeleteMutedWordParams{
+		ID:     wordID,
+		UserID: userID,
+	})
+	if err != nil {
+		log.Printf("error deleting muted word: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(204)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tristenkelly/chirpy/internal/auth"
+	"github.com/tristenkelly/chirpy/internal/database"
+)
+
+func (cfg *apiConfig) bookmarkChirp(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting token: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		log.Printf("token not valid: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		log.Printf("error converting ID to UUID %v", err)
+		w.WriteHeader(404)
+		return
+	}
+
+	chirp, ok, err := cfg.visibleChirp(r.Context(), userID, chirpID)
+	if err != nil {
+		log.Printf("error getting chirp: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	if !ok {
+		w.WriteHeader(404)
+		return
+	}
+
+	err = cfg.db.CreateBookmark(r.Context(), database.CreateBookmarkParams{
+		UserID:    userID,
+		ChirpID:   chirpID,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		log.Printf("error creating bookmark: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	cfg.recordEngagement(chirp, userID.String())
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) unbookmarkChirp(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting token: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		log.Printf("token not valid: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		log.Printf("error converting ID to UUID %v", err)
+		w.WriteHeader(404)
+		return
+	}
+
+	err = cfg.db.DeleteBookmark(r.Context(), database.DeleteBookmarkParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		log.Printf("error deleting bookmark: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+// getBookmarks lists the caller's bookmarks, most recently saved first.
+// Bookmarked chirps the caller can no longer see are skipped, including
+// ones whose author has since blocked the caller or been blocked by them.
+func (cfg *apiConfig) getBookmarks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting token: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		log.Printf("token not valid: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	before, beforeID, limit, err := pageParams(r)
+	if err != nil {
+		log.Printf("invalid pagination params: %v", err)
+		w.WriteHeader(400)
+		return
+	}
+
+	data, err := cfg.db.GetBookmarksPage(r.Context(), database.GetBookmarksPageParams{
+		UserID:          userID,
+		BeforeCreatedAt: before,
+		BeforeID:        beforeID,
+		PageSize:        limit,
+	})
+	if err != nil {
+		log.Printf("error getting bookmarks: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	filter, err := cfg.loadChirpFilter(r.Context(), userID, true)
+	if err != nil {
+		log.Printf("error loading chirp filter: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	var visible []database.Chirp
+	for _, row := range data {
+		if filter.canOpen(row.Chirp) {
+			visible = append(visible, row.Chirp)
+		}
+	}
+
+	chirps, err := cfg.chirpResponses(r.Context(), userID, visible)
+	if err != nil {
+		log.Printf("error building chirp responses: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	resp := chirpPage{Chirps: []chirpResponse{}}
+	resp.Chirps = append(resp.Chirps, chirps...)
+	if len(data) == int(limit) {
+		last := data[len(data)-1]
+		resp.NextCursor = encodeCursor(last.BookmarkedAt, last.Chirp.ID)
+	}
+
+	val, err := json.Marshal(resp)
+	if err != nil {
+		log.Printf("error marshalling bookmarks: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(val)
+}
@@ -0,0 +1,504 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tristenkelly/chirpy/internal/auth"
+	"github.com/tristenkelly/chirpy/internal/database"
+)
+
+const maxConversationMembers = 10
+
+type conversationMemberResponse struct {
+	UserID     uuid.UUID  `json:"user_id"`
+	JoinedAt   time.Time  `json:"joined_at"`
+	LastReadAt *time.Time `json:"last_read_at"`
+}
+
+type conversationResponse struct {
+	ID          uuid.UUID                    `json:"id"`
+	CreatedAt   time.Time                    `json:"created_at"`
+	UpdatedAt   time.Time                    `json:"updated_at"`
+	Members     []conversationMemberResponse `json:"members"`
+	UnreadCount int64                        `json:"unread_count"`
+}
+
+type messageResponse struct {
+	ID             uuid.UUID   `json:"id"`
+	CreatedAt      time.Time   `json:"created_at"`
+	ConversationID uuid.UUID   `json:"conversation_id"`
+	SenderID       uuid.UUID   `json:"sender_id"`
+	Body           string      `json:"body"`
+	ReadBy         []uuid.UUID `json:"read_by"`
+}
+
+func (cfg *apiConfig) conversationMembers(r *http.Request, conversationID uuid.UUID) ([]conversationMemberResponse, error) {
+	members, err := cfg.db.GetConversationMembers(r.Context(), conversationID)
+	if err != nil {
+		return nil, err
+	}
+	resp := []conversationMemberResponse{}
+	for _, m := range members {
+		member := conversationMemberResponse{
+			UserID:   m.UserID,
+			JoinedAt: m.JoinedAt,
+		}
+		if m.LastReadAt.Valid {
+			lastRead := m.LastReadAt.Time
+			member.LastReadAt = &lastRead
+		}
+		resp = append(resp, member)
+	}
+	return resp, nil
+}
+
+func (cfg *apiConfig) createConversation(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting token: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		log.Printf("token not valid: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	type paramaters struct {
+		MemberIDs []uuid.UUID `json:"member_ids"`
+	}
+
+	params := paramaters{}
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&params)
+	if err != nil {
+		log.Printf("error decoding conversation params: %v", err)
+		w.WriteHeader(400)
+		return
+	}
+
+	memberIDs := []uuid.UUID{userID}
+	seen := map[uuid.UUID]bool{userID: true}
+	for _, id := range params.MemberIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		memberIDs = append(memberIDs, id)
+	}
+	if len(memberIDs) < 2 || len(memberIDs) > maxConversationMembers {
+		w.WriteHeader(400)
+		return
+	}
+
+	for i, a := range memberIDs {
+		for _, b := range memberIDs[i+1:] {
+			blocked, err := cfg.isBlocked(r.Context(), a, b)
+			if err != nil {
+				log.Printf("error checking block: %v", err)
+				w.WriteHeader(500)
+				return
+			}
+			if blocked {
+				w.WriteHeader(403)
+				return
+			}
+		}
+	}
+
+	// The conversation and its members go in together, so a bad member ID
+	// doesn't leave a conversation behind with only some of them.
+	tx, err := cfg.sqlDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("error starting transaction: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	defer tx.Rollback()
+	q := cfg.withTx(tx)
+
+	now := time.Now()
+	conversation, err := q.CreateConversation(r.Context(), database.CreateConversationParams{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		CreatedBy: userID,
+	})
+	if err != nil {
+		log.Printf("error creating conversation: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	for _, id := range memberIDs {
+		err := q.AddConversationMember(r.Context(), database.AddConversationMemberParams{
+			ConversationID: conversation.ID,
+			UserID:         id,
+			JoinedAt:       now,
+		})
+		if err != nil {
+			log.Printf("error adding conversation member %v: %v", id, err)
+			w.WriteHeader(400)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("error committing conversation: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	members, err := cfg.conversationMembers(r, conversation.ID)
+	if err != nil {
+		log.Printf("error getting conversation members: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	val, err := json.Marshal(conversationResponse{
+		ID:        conversation.ID,
+		CreatedAt: conversation.CreatedAt,
+		UpdatedAt: conversation.UpdatedAt,
+		Members:   members,
+	})
+	if err != nil {
+		log.Printf("error marshalling conversation: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(201)
+	w.Write(val)
+}
+
+func (cfg *apiConfig) getConversations(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting token: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		log.Printf("token not valid: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	data, err := cfg.db.GetConversationsForUser(r.Context(), userID)
+	if err != nil {
+		log.Printf("error getting conversations: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	type conversationList struct {
+		Conversations []conversationResponse `json:"conversations"`
+		UnreadCount   int64                  `json:"unread_count"`
+	}
+
+	resp := conversationList{Conversations: []conversationResponse{}}
+	for _, c := range data {
+		members, err := cfg.conversationMembers(r, c.ID)
+		if err != nil {
+			log.Printf("error getting conversation members: %v", err)
+			w.WriteHeader(500)
+			return
+		}
+		resp.Conversations = append(resp.Conversations, conversationResponse{
+			ID:          c.ID,
+			CreatedAt:   c.CreatedAt,
+			UpdatedAt:   c.UpdatedAt,
+			Members:     members,
+			UnreadCount: c.UnreadCount,
+		})
+		resp.UnreadCount += c.UnreadCount
+	}
+
+	val, err := json.Marshal(resp)
+	if err != nil {
+		log.Printf("error marshalling conversations: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(val)
+}
+
+// conversationForMember resolves the {id} path value and checks the caller
+// belongs to it, writing the error status itself when it doesn't.
+func (cfg *apiConfig) conversationForMember(w http.ResponseWriter, r *http.Request, userID uuid.UUID) (database.Conversation, bool) {
+	conversationID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		log.Printf("error converting ID to UUID %v", err)
+		w.WriteHeader(404)
+		return database.Conversation{}, false
+	}
+
+	conversation, err := cfg.db.GetConversation(r.Context(), conversationID)
+	if errors.Is(err, sql.ErrNoRows) {
+		w.WriteHeader(404)
+		return database.Conversation{}, false
+	}
+	if err != nil {
+		log.Printf("error getting conversation: %v", err)
+		w.WriteHeader(500)
+		return database.Conversation{}, false
+	}
+
+	isMember, err := cfg.db.IsConversationMember(r.Context(), database.IsConversationMemberParams{
+		ConversationID: conversation.ID,
+		UserID:         userID,
+	})
+	if err != nil {
+		log.Printf("error checking conversation membership: %v", err)
+		w.WriteHeader(500)
+		return database.Conversation{}, false
+	}
+	if !isMember {
+		w.WriteHeader(404)
+		return database.Conversation{}, false
+	}
+	return conversation, true
+}
+
+func (cfg *apiConfig) getMessages(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting token: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		log.Printf("token not valid: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	conversation, ok := cfg.conversationForMember(w, r, userID)
+	if !ok {
+		return
+	}
+
+	before, beforeID, limit, err := pageParams(r)
+	if err != nil {
+		log.Printf("invalid pagination params: %v", err)
+		w.WriteHeader(400)
+		return
+	}
+
+	data, err := cfg.db.GetMessagesPage(r.Context(), database.GetMessagesPageParams{
+		ConversationID:  conversation.ID,
+		BeforeCreatedAt: before,
+		BeforeID:        beforeID,
+		PageSize:        limit,
+	})
+	if err != nil {
+		log.Printf("error getting messages: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	// Fetching the newest page counts as reading the conversation.
+	if r.URL.Query().Get("cursor") == "" && len(data) > 0 {
+		err := cfg.db.MarkConversationRead(r.Context(), database.MarkConversationReadParams{
+			ConversationID: conversation.ID,
+			UserID:         userID,
+			LastReadAt:     sql.NullTime{Time: data[0].CreatedAt, Valid: true},
+		})
+		if err != nil {
+			log.Printf("error marking conversation read: %v", err)
+		}
+	}
+
+	members, err := cfg.db.GetConversationMembers(r.Context(), conversation.ID)
+	if err != nil {
+		log.Printf("error getting conversation members: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	type messagePage struct {
+		Messages   []messageResponse `json:"messages"`
+		NextCursor string            `json:"next_cursor,omitempty"`
+	}
+
+	resp := messagePage{Messages: []messageResponse{}}
+	for _, msg := range data {
+		readBy := []uuid.UUID{}
+		for _, m := range members {
+			if m.UserID != msg.SenderID && m.LastReadAt.Valid && !m.LastReadAt.Time.Before(msg.CreatedAt) {
+				readBy = append(readBy, m.UserID)
+			}
+		}
+		resp.Messages = append(resp.Messages, messageResponse{
+			ID:             msg.ID,
+			CreatedAt:      msg.CreatedAt,
+			ConversationID: msg.ConversationID,
+			SenderID:       msg.SenderID,
+			Body:           msg.Body,
+			ReadBy:         readBy,
+		})
+	}
+	if len(data) == int(limit) {
+		last := data[len(data)-1]
+		resp.NextCursor = encodeCursor(last.CreatedAt, last.ID)
+	}
+
+	val, err := json.Marshal(resp)
+	if err != nil {
+		log.Printf("error marshalling messages: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(val)
+}
+
+func (cfg *apiConfig) createMessage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting token: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		log.Printf("token not valid: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	if cfg.isSuspended(r.Context(), userID) {
+		w.WriteHeader(403)
+		return
+	}
+
+	conversation, ok := cfg.conversationForMember(w, r, userID)
+	if !ok {
+		return
+	}
+
+	type paramaters struct {
+		Body string `json:"body"`
+	}
+
+	params := paramaters{}
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&params)
+	if err != nil {
+		log.Printf("error decoding message params: %v", err)
+		w.WriteHeader(400)
+		return
+	}
+	if params.Body == "" {
+		w.WriteHeader(400)
+		return
+	}
+	filtered := cfg.filter.Apply(params.Body)
+	if filtered.Rejected {
+		val, err := json.Marshal(struct {
+			Error string `json:"error"`
+		}{Error: "Message rejected: " + strings.Join(filtered.Reasons, ", ")})
+		if err != nil {
+			log.Printf("error marshalling message error: %v", err)
+			w.WriteHeader(500)
+			return
+		}
+		w.WriteHeader(400)
+		w.Write(val)
+		return
+	}
+
+	members, err := cfg.db.GetConversationMembers(r.Context(), conversation.ID)
+	if err != nil {
+		log.Printf("error getting conversation members: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	for _, m := range members {
+		if m.UserID == userID {
+			continue
+		}
+		blocked, err := cfg.isBlocked(r.Context(), userID, m.UserID)
+		if err != nil {
+			log.Printf("error checking block: %v", err)
+			w.WriteHeader(500)
+			return
+		}
+		if blocked {
+			w.WriteHeader(403)
+			return
+		}
+	}
+
+	now := time.Now()
+	msg, err := cfg.db.CreateMessage(r.Context(), database.CreateMessageParams{
+		ID:             uuid.New(),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		ConversationID: conversation.ID,
+		SenderID:       userID,
+		Body:           filtered.Text,
+	})
+	if err != nil {
+		log.Printf("error creating message: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	err = cfg.db.TouchConversation(r.Context(), database.TouchConversationParams{
+		ID:        conversation.ID,
+		UpdatedAt: now,
+	})
+	if err != nil {
+		log.Printf("error updating conversation: %v", err)
+	}
+
+	// The sender has obviously read everything up to their own message.
+	err = cfg.db.MarkConversationRead(r.Context(), database.MarkConversationReadParams{
+		ConversationID: conversation.ID,
+		UserID:         userID,
+		LastReadAt:     sql.NullTime{Time: msg.CreatedAt, Valid: true},
+	})
+	if err != nil {
+		log.Printf("error marking conversation read: %v", err)
+	}
+
+	val, err := json.Marshal(messageResponse{
+		ID:             msg.ID,
+		CreatedAt:      msg.CreatedAt,
+		ConversationID: msg.ConversationID,
+		SenderID:       msg.SenderID,
+		Body:           msg.Body,
+		ReadBy:         []uuid.UUID{},
+	})
+	if err != nil {
+		log.Printf("error marshalling message: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(201)
+	w.Write(val)
+}
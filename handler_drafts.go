@@ -0,0 +1,536 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tristenkelly/chirpy/internal/auth"
+	"github.com/tristenkelly/chirpy/internal/chirplen"
+	"github.com/tristenkelly/chirpy/internal/database"
+	"github.com/tristenkelly/chirpy/internal/entitlements"
+	"github.com/tristenkelly/chirpy/internal/filter"
+	"github.com/tristenkelly/chirpy/internal/unfurl"
+)
+
+const (
+	schedulerInterval  = 10 * time.Second
+	schedulerBatchSize = 20
+	maxScheduleAhead   = 365 * 24 * time.Hour
+
+	notificationScheduledChirpFailed = "scheduled_chirp_failed"
+)
+
+type draftResponse struct {
+	ID             uuid.UUID  `json:"id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	Body           string     `json:"body"`
+	PublishAt      *time.Time `json:"publish_at,omitempty"`
+	Visibility     string     `json:"visibility"`
+	ContentWarning string     `json:"content_warning"`
+	Sensitive      bool       `json:"sensitive"`
+}
+
+func draftToResponse(d database.Draft) draftResponse {
+	resp := draftResponse{
+		ID:             d.ID,
+		CreatedAt:      d.CreatedAt,
+		UpdatedAt:      d.UpdatedAt,
+		Body:           d.Body,
+		Visibility:     d.Visibility,
+		ContentWarning: d.ContentWarning,
+		Sensitive:      d.Sensitive,
+	}
+	if d.PublishAt.Valid {
+		resp.PublishAt = &d.PublishAt.Time
+	}
+	return resp
+}
+
+// checkChirpBody runs the checks a chirp body must pass to be published:
+// the content filter and the author's length limit. It returns a message
+// for the author when the body can't be published.
+func (cfg *apiConfig) checkChirpBody(author database.User, body string) (filter.Result, string) {
+	filtered := cfg.filter.Apply(body)
+	if filtered.Rejected {
+		return filtered, "Chirp rejected: " + strings.Join(filtered.Reasons, ", ")
+	}
+	if chirplen.Count(body, cfg.urlWeight) > cfg.limitsFor(author).MaxChirpLength {
+		return filtered, "Chirp is too long"
+	}
+	return filtered, ""
+}
+
+// draftFields is the editable part of a draft.
+type draftFields struct {
+	Body           string
+	PublishAt      sql.NullTime
+	Visibility     string
+	ContentWarning string
+	Sensitive      bool
+}
+
+// draftParams reads and checks the body of a draft create or update. Drafts
+// can hold anything; a draft with a publish_at time must be publishable as
+// it stands and scheduled in the future. On failure it writes the response
+// itself.
+func (cfg *apiConfig) draftParams(w http.ResponseWriter, r *http.Request, author database.User) (draftFields, bool) {
+	type paramaters struct {
+		Body           string     `json:"body"`
+		PublishAt      *time.Time `json:"publish_at"`
+		Visibility     string     `json:"visibility"`
+		ContentWarning string     `json:"content_warning"`
+		Sensitive      bool       `json:"sensitive"`
+	}
+
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+
+	params := paramaters{}
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&params)
+	if err != nil {
+		log.Printf("error decoding draft: %v", err)
+		w.WriteHeader(400)
+		return draftFields{}, false
+	}
+	if params.PublishAt != nil && !cfg.requireFeature(w, author, entitlements.ScheduledPosts) {
+		return draftFields{}, false
+	}
+	if params.Visibility == "" {
+		params.Visibility = visibilityPublic
+	}
+	fields := draftFields{
+		Body:           params.Body,
+		Visibility:     params.Visibility,
+		ContentWarning: params.ContentWarning,
+		Sensitive:      params.Sensitive,
+	}
+
+	respError := errorResponse{}
+	if !visibilities[params.Visibility] {
+		respError.Error = "Invalid visibility " + params.Visibility
+	} else if params.PublishAt != nil {
+		ahead := time.Until(*params.PublishAt)
+		if ahead <= 0 {
+			respError.Error = "publish_at must be in the future"
+		} else if ahead > maxScheduleAhead {
+			respError.Error = "Chirps can be scheduled up to a year ahead"
+		} else if _, msg := cfg.checkChirpBody(author, params.Body); msg != "" {
+			respError.Error = msg
+		} else if _, msg := cfg.checkContentWarning(params.ContentWarning); msg != "" {
+			respError.Error = msg
+		}
+		// Timestamps are stored as local wall-clock time, like every other
+		// column, so the scheduler's time.Now() compares correctly.
+		fields.PublishAt = sql.NullTime{Time: params.PublishAt.Local(), Valid: true}
+	}
+	if respError.Error != "" {
+		val, _ := json.Marshal(respError)
+		w.WriteHeader(400)
+		w.Write(val)
+		return draftFields{}, false
+	}
+	return fields, true
+}
+
+// draftAuthor authenticates the caller and loads them, refusing suspended
+// accounts.
+func (cfg *apiConfig) draftAuthor(w http.ResponseWriter, r *http.Request) (database.User, bool) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting token: %v", err)
+		w.WriteHeader(401)
+		return database.User{}, false
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		log.Printf("token not valid: %v", err)
+		w.WriteHeader(401)
+		return database.User{}, false
+	}
+
+	author, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		log.Printf("error getting user: %v", err)
+		w.WriteHeader(401)
+		return database.User{}, false
+	}
+	if author.SuspendedAt.Valid {
+		w.WriteHeader(403)
+		return database.User{}, false
+	}
+	return author, true
+}
+
+func (cfg *apiConfig) createDraft(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	author, ok := cfg.draftAuthor(w, r)
+	if !ok {
+		return
+	}
+	fields, ok := cfg.draftParams(w, r, author)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	draft, err := cfg.db.CreateDraft(r.Context(), database.CreateDraftParams{
+		ID:             uuid.New(),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		UserID:         author.ID,
+		Body:           fields.Body,
+		PublishAt:      fields.PublishAt,
+		Visibility:     fields.Visibility,
+		ContentWarning: fields.ContentWarning,
+		Sensitive:      fields.Sensitive,
+	})
+	if err != nil {
+		log.Printf("error creating draft: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	val, err := json.Marshal(draftToResponse(draft))
+	if err != nil {
+		log.Printf("error marshalling draft: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(201)
+	w.Write(val)
+}
+
+// getDrafts lists the caller's drafts, or their scheduled chirps with
+// ?scheduled=true.
+func (cfg *apiConfig) getDrafts(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting token: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		log.Printf("token not valid: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	var drafts []database.Draft
+	if r.URL.Query().Get("scheduled") == "true" {
+		drafts, err = cfg.db.GetScheduledChirps(r.Context(), userID)
+	} else {
+		drafts, err = cfg.db.GetDrafts(r.Context(), userID)
+	}
+	if err != nil {
+		log.Printf("error getting drafts: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	resp := []draftResponse{}
+	for _, d := range drafts {
+		resp = append(resp, draftToResponse(d))
+	}
+	val, err := json.Marshal(resp)
+	if err != nil {
+		log.Printf("error marshalling drafts: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(val)
+}
+
+func (cfg *apiConfig) getDraft(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting token: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		log.Printf("token not valid: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	draftID, err := uuid.Parse(r.PathValue("draftID"))
+	if err != nil {
+		log.Printf("error converting ID to UUID %v", err)
+		w.WriteHeader(404)
+		return
+	}
+
+	draft, err := cfg.db.GetDraft(r.Context(), database.GetDraftParams{ID: draftID, UserID: userID})
+	if err != nil {
+		w.WriteHeader(404)
+		return
+	}
+
+	val, err := json.Marshal(draftToResponse(draft))
+	if err != nil {
+		log.Printf("error marshalling draft: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(val)
+}
+
+// updateDraft replaces a draft's body and schedule. Setting publish_at to
+// null turns a scheduled chirp back into a draft.
+func (cfg *apiConfig) updateDraft(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	author, ok := cfg.draftAuthor(w, r)
+	if !ok {
+		return
+	}
+
+	draftID, err := uuid.Parse(r.PathValue("draftID"))
+	if err != nil {
+		log.Printf("error converting ID to UUID %v", err)
+		w.WriteHeader(404)
+		return
+	}
+
+	fields, ok := cfg.draftParams(w, r, author)
+	if !ok {
+		return
+	}
+
+	// A draft the scheduler is publishing stays locked until it's gone, so
+	// this either lands first or finds nothing to update.
+	draft, err := cfg.db.UpdateDraft(r.Context(), database.UpdateDraftParams{
+		ID:             draftID,
+		UserID:         author.ID,
+		Body:           fields.Body,
+		PublishAt:      fields.PublishAt,
+		Visibility:     fields.Visibility,
+		ContentWarning: fields.ContentWarning,
+		Sensitive:      fields.Sensitive,
+		UpdatedAt:      time.Now(),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		w.WriteHeader(404)
+		return
+	}
+	if err != nil {
+		log.Printf("error updating draft: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	val, err := json.Marshal(draftToResponse(draft))
+	if err != nil {
+		log.Printf("error marshalling draft: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(val)
+}
+
+// deleteDraft discards a draft or cancels a scheduled chirp.
+func (cfg *apiConfig) deleteDraft(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting token: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		log.Printf("token not valid: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	draftID, err := uuid.Parse(r.PathValue("draftID"))
+	if err != nil {
+		log.Printf("error converting ID to UUID %v", err)
+		w.WriteHeader(404)
+		return
+	}
+
+	deleted, err := cfg.db.DeleteDraft(r.Context(), database.DeleteDraftParams{ID: draftID, UserID: userID})
+	if err != nil {
+		log.Printf("error deleting draft: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	if deleted == 0 {
+		w.WriteHeader(404)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+// runScheduler publishes scheduled chirps once their time comes.
+func (cfg *apiConfig) runScheduler(ctx context.Context) {
+	ticker := time.NewTicker(schedulerInterval)
+	defer ticker.Stop()
+	for {
+		// A full batch means there may be more due right away.
+		for cfg.publishDueChirps(ctx) == schedulerBatchSize {
+			if ctx.Err() != nil {
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// publishDueChirps publishes one batch of due scheduled chirps and returns
+// how many it claimed. The rows are claimed with FOR UPDATE SKIP LOCKED and
+// each chirp is created in the same transaction that deletes its draft, so
+// with several replicas running every scheduled chirp is published exactly
+// once. Each draft runs under its own savepoint: one that fails is rolled
+// back alone and handed back to its author, rather than holding up the rest
+// of the batch on every tick.
+func (cfg *apiConfig) publishDueChirps(ctx context.Context) int {
+	tx, err := cfg.sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("error starting scheduler transaction: %v", err)
+		return 0
+	}
+	defer tx.Rollback()
+	q := cfg.withTx(tx)
+
+	now := time.Now()
+	due, err := q.ClaimDueDrafts(ctx, database.ClaimDueDraftsParams{
+		PublishAt: sql.NullTime{Time: now, Valid: true},
+		Limit:     schedulerBatchSize,
+	})
+	if err != nil {
+		log.Printf("error claiming scheduled chirps: %v", err)
+		return 0
+	}
+
+	var published []scheduledChirp
+	var failed []uuid.UUID
+	for _, d := range due {
+		if _, err := tx.ExecContext(ctx, "SAVEPOINT scheduled_chirp"); err != nil {
+			log.Printf("error starting savepoint for scheduled chirp %v: %v", d.ID, err)
+			return 0
+		}
+		p, ok, err := cfg.publishDraft(ctx, q, d, now)
+		if err != nil {
+			log.Printf("error publishing scheduled chirp %v: %v", d.ID, err)
+			if _, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT scheduled_chirp"); err != nil {
+				log.Printf("error rolling back scheduled chirp %v: %v", d.ID, err)
+				return 0
+			}
+			err = q.UnscheduleDraft(ctx, database.UnscheduleDraftParams{ID: d.ID, UpdatedAt: now})
+			if err != nil {
+				log.Printf("error unscheduling chirp %v: %v", d.ID, err)
+				return 0
+			}
+			ok = false
+		}
+		if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT scheduled_chirp"); err != nil {
+			log.Printf("error releasing savepoint for scheduled chirp %v: %v", d.ID, err)
+			return 0
+		}
+		if !ok {
+			failed = append(failed, d.UserID)
+			continue
+		}
+		published = append(published, p)
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("error committing scheduled chirps: %v", err)
+		return 0
+	}
+
+	for _, p := range published {
+		if p.flagged {
+			cfg.flagForReview(ctx, p.chirp, p.reasons)
+		}
+		cfg.queueLinkPreview(unfurl.FirstURL(p.chirp.Body))
+		cfg.metrics.Chirps.Inc()
+		cfg.federateChirp(ctx, p.chirp)
+		cfg.emitChirpCreated(ctx, p.chirp)
+	}
+	for _, userID := range failed {
+		cfg.notify(ctx, userID, notificationScheduledChirpFailed, uuid.NullUUID{})
+	}
+	return len(due)
+}
+
+// scheduledChirp is a chirp published from a draft, along with whether the
+// filter wants it reviewed.
+type scheduledChirp struct {
+	chirp   database.Chirp
+	flagged bool
+	reasons []string
+}
+
+// publishDraft turns a claimed draft into a chirp using q. It reports false
+// when the draft can no longer be published and was handed back to its
+// author instead.
+func (cfg *apiConfig) publishDraft(ctx context.Context, q *database.Queries, d database.Draft, now time.Time) (scheduledChirp, bool, error) {
+	author, err := q.GetUserByID(ctx, d.UserID)
+	if err != nil {
+		return scheduledChirp{}, false, err
+	}
+	// Rules and limits may have changed since the chirp was scheduled. If
+	// it can no longer be published, hand it back to the author as a
+	// draft.
+	filtered, msg := cfg.checkChirpBody(author, d.Body)
+	cwFiltered, cwMsg := cfg.checkContentWarning(d.ContentWarning)
+	if msg != "" || cwMsg != "" || author.SuspendedAt.Valid {
+		err := q.UnscheduleDraft(ctx, database.UnscheduleDraftParams{ID: d.ID, UpdatedAt: now})
+		return scheduledChirp{}, false, err
+	}
+
+	chirp, err := q.CreateChirp(ctx, database.CreateChirpParams{
+		ID:             uuid.New(),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		Body:           filtered.Text,
+		UserID:         d.UserID,
+		Visibility:     d.Visibility,
+		ContentWarning: cwFiltered.Text,
+		Sensitive:      d.Sensitive,
+	})
+	if err != nil {
+		return scheduledChirp{}, false, err
+	}
+	_, err = q.DeleteDraft(ctx, database.DeleteDraftParams{ID: d.ID, UserID: d.UserID})
+	if err != nil {
+		return scheduledChirp{}, false, err
+	}
+	return scheduledChirp{
+		chirp:   chirp,
+		flagged: filtered.Flagged || cwFiltered.Flagged,
+		reasons: append(filtered.Reasons, cwFiltered.Reasons...),
+	}, true, nil
+}
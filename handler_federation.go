@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tristenkelly/chirpy/internal/activitypub"
+	"github.com/tristenkelly/chirpy/internal/auth"
+	"github.com/tristenkelly/chirpy/internal/database"
+)
+
+type remoteFollowResponse struct {
+	ID        uuid.UUID `json:"id"`
+	Actor     string    `json:"actor"`
+	Accepted  bool      `json:"accepted"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func remoteFollowToResponse(f database.RemoteFollow) remoteFollowResponse {
+	return remoteFollowResponse{
+		ID:        f.ID,
+		Actor:     f.ActorID,
+		Accepted:  f.AcceptedAt.Valid,
+		CreatedAt: f.CreatedAt,
+	}
+}
+
+// createRemoteFollow follows an account on another server, given as an
+// actor URL or as user@host. The follow is pending until the remote
+// server sends an Accept, so this returns 202.
+func (cfg *apiConfig) createRemoteFollow(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if !cfg.federating(w) {
+		return
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting token: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		log.Printf("token not valid: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	type paramaters struct {
+		Account string `json:"account"`
+	}
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+
+	params := paramaters{}
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&params)
+	if err != nil {
+		log.Printf("error decoding parameters: %v", err)
+		w.WriteHeader(400)
+		return
+	}
+
+	actorID := strings.TrimSpace(params.Account)
+	if !strings.HasPrefix(actorID, "http://") && !strings.HasPrefix(actorID, "https://") {
+		scheme := "https"
+		if u, err := url.Parse(cfg.publicURL); err == nil && u.Scheme == "http" {
+			scheme = "http"
+		}
+		actorID, err = cfg.apClient.Webfinger(r.Context(), actorID, scheme)
+	}
+	var actor database.RemoteActor
+	if err == nil {
+		actor, err = cfg.remoteActor(r.Context(), actorID, false)
+	}
+	if err != nil {
+		log.Printf("error resolving %q: %v", params.Account, err)
+		val, _ := json.Marshal(errorResponse{Error: "Account not found"})
+		w.WriteHeader(404)
+		w.Write(val)
+		return
+	}
+	if strings.HasPrefix(actor.ID, cfg.publicURL+"/") {
+		val, _ := json.Marshal(errorResponse{Error: "Account is on this server"})
+		w.WriteHeader(400)
+		w.Write(val)
+		return
+	}
+
+	follow, err := cfg.db.CreateRemoteFollow(r.Context(), database.CreateRemoteFollowParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UserID:    userID,
+		ActorID:   actor.ID,
+	})
+	if err != nil {
+		log.Printf("error creating remote follow: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	// Following again resends the Follow, in case the first one was lost.
+	activity, err := activitypub.NewActivity(cfg.followActivityURL(follow.ID), "Follow", cfg.actorURL(userID), actor.ID, []string{actor.ID}, nil)
+	if err == nil {
+		err = cfg.queueDelivery(r.Context(), userID, actor.InboxUrl, activity)
+	}
+	if err != nil {
+		log.Printf("error queueing follow: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	val, err := json.Marshal(remoteFollowToResponse(follow))
+	if err != nil {
+		log.Printf("error marshalling remote follow: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(202)
+	w.Write(val)
+}
+
+func (cfg *apiConfig) getRemoteFollows(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting token: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		log.Printf("token not valid: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	follows, err := cfg.db.GetRemoteFollows(r.Context(), userID)
+	if err != nil {
+		log.Printf("error getting remote follows: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	resp := []remoteFollowResponse{}
+	for _, f := range follows {
+		resp = append(resp, remoteFollowToResponse(f))
+	}
+	val, err := json.Marshal(resp)
+	if err != nil {
+		log.Printf("error marshalling remote follows: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(val)
+}
+
+// deleteRemoteFollow unfollows a remote account, sending an Undo for the
+// original Follow.
+func (cfg *apiConfig) deleteRemoteFollow(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting token: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		log.Printf("token not valid: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	followID, err := uuid.Parse(r.PathValue("followID"))
+	if err != nil {
+		w.WriteHeader(404)
+		return
+	}
+	follow, err := cfg.db.GetRemoteFollow(r.Context(), database.GetRemoteFollowParams{ID: followID, UserID: userID})
+	if err != nil {
+		w.WriteHeader(404)
+		return
+	}
+
+	// The follow is dropped here even if the Undo can't be sent; the
+	// remote server will stop hearing from us either way.
+	if cfg.publicURL != "" {
+		if err := cfg.sendUndoFollow(r.Context(), follow); err != nil {
+			log.Printf("error queueing undo for follow %v: %v", follow.ID, err)
+		}
+	}
+
+	err = cfg.db.DeleteRemoteFollow(r.Context(), database.DeleteRemoteFollowParams{ID: followID, UserID: userID})
+	if err != nil {
+		log.Printf("error deleting remote follow: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+// sendUndoFollow queues an Undo for one of our Follow activities.
+func (cfg *apiConfig) sendUndoFollow(ctx context.Context, follow database.RemoteFollow) error {
+	remote, err := cfg.remoteActor(ctx, follow.ActorID, false)
+	if err != nil {
+		return err
+	}
+	actor := cfg.actorURL(follow.UserID)
+	followActivity, err := activitypub.NewActivity(cfg.followActivityURL(follow.ID), "Follow", actor, remote.ID, nil, nil)
+	if err != nil {
+		return err
+	}
+	followActivity.Context = nil
+	undo, err := activitypub.NewActivity(cfg.followActivityURL(follow.ID)+"#undo", "Undo", actor, followActivity, []string{remote.ID}, nil)
+	if err != nil {
+		return err
+	}
+	return cfg.queueDelivery(ctx, follow.UserID, remote.InboxUrl, undo)
+}
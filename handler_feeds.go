@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tristenkelly/chirpy/internal/database"
+	"github.com/tristenkelly/chirpy/internal/feed"
+)
+
+const (
+	feedSize         = 20
+	feedTitleLength  = 80
+	feedCacheControl = "public, max-age=300"
+)
+
+var feedContentTypes = map[string]string{
+	"feed.atom": feed.AtomContentType,
+	"feed.rss":  feed.RSSContentType,
+	"feed.json": feed.JSONContentType,
+}
+
+var feedTagPattern = regexp.MustCompile(`^[[:alnum:]_]+$`)
+
+// baseURL is the absolute root of this instance, for links that leave the
+// API, such as those in feeds. PUBLIC_URL wins when it's set.
+func (cfg *apiConfig) baseURL(r *http.Request) string {
+	if cfg.publicURL != "" {
+		return cfg.publicURL
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// getUserFeed serves a user's public chirps as feed.atom, feed.rss or
+// feed.json, so they can be followed from a feed reader without an account.
+func (cfg *apiConfig) getUserFeed(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		w.WriteHeader(404)
+		return
+	}
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil || user.SuspendedAt.Valid {
+		w.WriteHeader(404)
+		return
+	}
+
+	chirps, err := cfg.db.GetPublicChirpsForUser(r.Context(), database.GetPublicChirpsForUserParams{
+		UserID: userID,
+		Limit:  feedSize,
+	})
+	if err != nil {
+		log.Printf("error getting chirps: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	base := cfg.baseURL(r)
+	cfg.serveFeed(w, r, feed.Feed{
+		Title:       "Chirps by " + userID.String(),
+		Description: "Public chirps by " + userID.String() + " on Chirpy",
+		Link:        base + "/api/chirps?author_id=" + userID.String(),
+		FeedURL:     base + r.URL.Path,
+	}, chirps)
+}
+
+// getTagFeed serves public chirps with a hashtag, in the same formats as
+// getUserFeed.
+func (cfg *apiConfig) getTagFeed(w http.ResponseWriter, r *http.Request) {
+	tag := strings.ToLower(strings.TrimPrefix(r.PathValue("tag"), "#"))
+	if !feedTagPattern.MatchString(tag) {
+		w.WriteHeader(404)
+		return
+	}
+
+	chirps, err := cfg.db.GetPublicChirpsForHashtag(r.Context(), database.GetPublicChirpsForHashtagParams{
+		Tag:      tag,
+		MaxItems: feedSize,
+	})
+	if err != nil {
+		log.Printf("error getting chirps for #%s: %v", tag, err)
+		w.WriteHeader(500)
+		return
+	}
+
+	base := cfg.baseURL(r)
+	cfg.serveFeed(w, r, feed.Feed{
+		Title:       "#" + tag + " on Chirpy",
+		Description: "Public chirps tagged #" + tag,
+		Link:        base + "/app/",
+		FeedURL:     base + r.URL.Path,
+	}, chirps)
+}
+
+// serveFeed renders chirps, newest first, in the format named by the
+// {feed} path segment. Only chirps anyone could see in a listing are
+// included. The ETag is a hash of the document, so readers polling with
+// If-None-Match get a 304 until something changes. There is no
+// Last-Modified: the newest updated_at shown doesn't move when a chirp is
+// deleted or hidden, so If-Modified-Since would keep serving a stale feed.
+func (cfg *apiConfig) serveFeed(w http.ResponseWriter, r *http.Request, f feed.Feed, chirps []database.Chirp) {
+	format := r.PathValue("feed")
+	contentType, ok := feedContentTypes[format]
+	if !ok {
+		w.WriteHeader(404)
+		return
+	}
+
+	filter, err := cfg.loadChirpFilter(r.Context(), uuid.Nil, false)
+	if err != nil {
+		log.Printf("error loading chirp filter: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	var visible []database.Chirp
+	for _, chirp := range chirps {
+		if filter.allows(chirp) {
+			visible = append(visible, chirp)
+		}
+		if len(visible) == feedSize {
+			break
+		}
+	}
+	resp, err := cfg.chirpResponses(r.Context(), uuid.Nil, visible)
+	if err != nil {
+		log.Printf("error building chirp responses: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	base := cfg.baseURL(r)
+	for _, chirp := range resp {
+		if chirp.UpdatedAt.After(f.Updated) {
+			f.Updated = chirp.UpdatedAt
+		}
+		link := chirpPageURL(base, chirp.ID)
+		item := feed.Item{
+			ID:        link,
+			URL:       link,
+			Title:     feedItemTitle(chirp),
+			Text:      chirp.Body,
+			Author:    chirp.UserID.String(),
+			Published: chirp.CreatedAt,
+			Updated:   chirp.UpdatedAt,
+		}
+		for _, m := range chirp.Media {
+			mediaURL := m.URL
+			if strings.HasPrefix(mediaURL, "/") {
+				mediaURL = base + mediaURL
+			}
+			item.Attachments = append(item.Attachments, feed.Attachment{URL: mediaURL, MimeType: m.ContentType})
+		}
+		f.Items = append(f.Items, item)
+	}
+	// An empty feed still needs a date; it never changes, so a fixed one
+	// keeps it cacheable.
+	if f.Updated.IsZero() {
+		f.Updated = time.Unix(0, 0)
+	}
+
+	var body []byte
+	switch format {
+	case "feed.atom":
+		body, err = f.Atom()
+	case "feed.rss":
+		body, err = f.RSS()
+	default:
+		body, err = f.JSON()
+	}
+	if err != nil {
+		log.Printf("error rendering feed: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	sum := sha256.Sum256(body)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	w.Header().Set("Cache-Control", feedCacheControl)
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(body))
+}
+
+// feedItemTitle is the content warning when there is one, so a reader
+// shows it before the chirp, and otherwise the start of the chirp.
+func feedItemTitle(chirp chirpResponse) string {
+	if chirp.ContentWarning != "" {
+		return "CW: " + chirp.ContentWarning
+	}
+	title, _, _ := strings.Cut(chirp.Body, "\n")
+	return truncateRunes(title, feedTitleLength)
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tristenkelly/chirpy/internal/database"
+	"github.com/tristenkelly/chirpy/internal/filter"
+)
+
+// dbFilterSource loads filter rules from the filter_rules table.
+func dbFilterSource(db *database.Queries) filter.Source {
+	return func(ctx context.Context) ([]filter.Rule, error) {
+		rows, err := db.GetFilterRules(ctx)
+		if err != nil {
+			return nil, err
+		}
+		rules := []filter.Rule{}
+		for _, row := range rows {
+			rules = append(rules, filter.Rule{
+				Pattern: row.Pattern,
+				Kind:    filter.Kind(row.Kind),
+				Action:  filter.Action(row.Action),
+				Reason:  row.Reason,
+			})
+		}
+		return rules, nil
+	}
+}
+
+type storedFilterRule struct {
+	ID uuid.UUID `json:"id"`
+	filter.Rule
+}
+
+// flagForReview puts a chirp the filter flagged into the moderation queue.
+// Flags raised by the filter have no reporter.
+func (cfg *apiConfig) flagForReview(ctx context.Context, chirp database.Chirp, reasons []string) {
+	now := time.Now()
+	_, err := cfg.db.CreateReport(ctx, database.CreateReportParams{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		ChirpID:   uuid.NullUUID{UUID: chirp.ID, Valid: true},
+		AuthorID:  chirp.UserID,
+		Reason:    "filter",
+		Details:   strings.Join(reasons, "; "),
+	})
+	if err != nil {
+		log.Printf("error flagging chirp %v for review: %v", chirp.ID, err)
+	}
+}
+
+func (cfg *apiConfig) getFilterRules(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if _, ok := cfg.moderator(w, r); !ok {
+		return
+	}
+
+	type ruleList struct {
+		Source string             `json:"source"`
+		Active []filter.Rule      `json:"active"`
+		Stored []storedFilterRule `json:"stored,omitempty"`
+	}
+
+	resp := ruleList{Source: "database", Active: cfg.filter.Rules()}
+	if cfg.filterFile != "" {
+		resp.Source = cfg.filterFile
+	} else {
+		rows, err := cfg.db.GetFilterRules(r.Context())
+		if err != nil {
+			log.Printf("error getting filter rules: %v", err)
+			w.WriteHeader(500)
+			return
+		}
+		for _, row := range rows {
+			resp.Stored = append(resp.Stored, storedFilterRule{
+				ID: row.ID,
+				Rule: filter.Rule{
+					Pattern: row.Pattern,
+					Kind:    filter.Kind(row.Kind),
+					Action:  filter.Action(row.Action),
+					Reason:  row.Reason,
+				},
+			})
+		}
+	}
+
+	val, err := json.Marshal(resp)
+	if err != nil {
+		log.Printf("error marshalling filter rules: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(val)
+}
+
+func (cfg *apiConfig) createFilterRule(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if _, ok := cfg.moderator(w, r); !ok {
+		return
+	}
+	if cfg.filterFile != "" {
+		// Rules come from a file; edit the file and reload instead.
+		w.WriteHeader(409)
+		return
+	}
+
+	params := filter.Rule{}
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&params)
+	if err != nil {
+		log.Printf("error decoding filter rule: %v", err)
+		w.WriteHeader(400)
+		return
+	}
+	if params.Kind == "" {
+		params.Kind = filter.KindWord
+	}
+	if params.Action == "" {
+		params.Action = filter.ActionMask
+	}
+
+	// Compile the rule before storing it so a bad regex can't break the
+	// next reload.
+	probe := filter.New(func(context.Context) ([]filter.Rule, error) {
+		return []filter.Rule{params}, nil
+	})
+	if err := probe.Reload(r.Context()); err != nil {
+		log.Printf("invalid filter rule: %v", err)
+		w.WriteHeader(400)
+		return
+	}
+
+	rule, err := cfg.db.CreateFilterRule(r.Context(), database.CreateFilterRuleParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		Pattern:   params.Pattern,
+		Kind:      string(params.Kind),
+		Action:    string(params.Action),
+		Reason:    params.Reason,
+	})
+	if err != nil {
+		log.Printf("error creating filter rule: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	if err := cfg.filter.Reload(r.Context()); err != nil {
+		log.Printf("error reloading filter rules: %v", err)
+	}
+
+	val, err := json.Marshal(storedFilterRule{ID: rule.ID, Rule: params})
+	if err != nil {
+		log.Printf("error marshalling filter rule: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(201)
+	w.Write(val)
+}
+
+func (cfg *apiConfig) deleteFilterRule(w http.ResponseWriter, r *http.Request) {
+	if _, ok := cfg.moderator(w, r); !ok {
+		return
+	}
+	if cfg.filterFile != "" {
+		w.WriteHeader(409)
+		return
+	}
+
+	ruleID, err := uuid.Parse(r.PathValue("ruleID"))
+	if err != nil {
+		log.Printf("error converting ID to UUID %v", err)
+		w.WriteHeader(404)
+		return
+	}
+
+	err = cfg.db.DeleteFilterRule(r.Context(), ruleID)
+	if err != nil {
+		log.Printf("error deleting filter rule: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	if err := cfg.filter.Reload(r.Context()); err != nil {
+		log.Printf("error reloading filter rules: %v", err)
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) reloadFilterRules(w http.ResponseWriter, r *http.Request) {
+	if _, ok := cfg.moderator(w, r); !ok {
+		return
+	}
+
+	err := cfg.filter.Reload(r.Context())
+	if err != nil {
+		log.Printf("error reloading filter rules: %v", err)
+		w.Header().Set("Content-Type", "application/json")
+		val, _ := json.Marshal(struct {
+			Error string `json:"error"`
+		}{Error: err.Error()})
+		w.WriteHeader(400)
+		w.Write(val)
+		return
+	}
+	w.WriteHeader(204)
+}
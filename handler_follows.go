@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tristenkelly/chirpy/internal/auth"
+	"github.com/tristenkelly/chirpy/internal/database"
+)
+
+func (cfg *apiConfig) followUser(w http.ResponseWriter, r *http.Request) {
+	userID, targetID, ok := cfg.relationTarget(w, r)
+	if !ok {
+		return
+	}
+	blocked, err := cfg.isBlocked(r.Context(), userID, targetID)
+	if err != nil {
+		log.Printf("error checking block: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	if blocked {
+		w.WriteHeader(403)
+		return
+	}
+	inserted, err := cfg.db.FollowUser(r.Context(), database.FollowUserParams{
+		FollowerID: userID,
+		FolloweeID: targetID,
+		CreatedAt:  time.Now(),
+	})
+	if err != nil {
+		if isMissingRow(err) {
+			w.WriteHeader(404)
+			return
+		}
+		log.Printf("error following user: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	if inserted > 0 {
+		cfg.emitWebhook(r.Context(), targetID, webhookFollowerNew, map[string]any{"follower_id": userID})
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) unfollowUser(w http.ResponseWriter, r *http.Request) {
+	userID, targetID, ok := cfg.relationTarget(w, r)
+	if !ok {
+		return
+	}
+	err := cfg.db.UnfollowUser(r.Context(), database.UnfollowUserParams{
+		FollowerID: userID,
+		FolloweeID: targetID,
+	})
+	if err != nil {
+		log.Printf("error unfollowing user: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) getFollows(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting token: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		log.Printf("token not valid: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	following, err := cfg.db.GetFollowing(r.Context(), userID)
+	if err != nil {
+		log.Printf("error getting followed users: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	followers, err := cfg.db.GetFollowers(r.Context(), userID)
+	if err != nil {
+		log.Printf("error getting followers: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	type relation struct {
+		UserID    uuid.UUID `json:"user_id"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+
+	type followList struct {
+		Following []relation `json:"following"`
+		Followers []relation `json:"followers"`
+	}
+
+	resp := followList{Following: []relation{}, Followers: []relation{}}
+	for _, f := range following {
+		resp.Following = append(resp.Following, relation{UserID: f.FolloweeID, CreatedAt: f.CreatedAt})
+	}
+	for _, f := range followers {
+		resp.Followers = append(resp.Followers, relation{UserID: f.FollowerID, CreatedAt: f.CreatedAt})
+	}
+
+	val, err := json.Marshal(resp)
+	if err != nil {
+		log.Printf("error marshalling follows: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(val)
+}
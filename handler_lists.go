@@ -0,0 +1,443 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/tristenkelly/chirpy/internal/auth"
+	"github.com/tristenkelly/chirpy/internal/database"
+)
+
+const (
+	maxListsPerUser = 100
+	maxListMembers  = 500
+	maxListNameLen  = 100
+)
+
+type listResponse struct {
+	ID        uuid.UUID   `json:"id"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+	OwnerID   uuid.UUID   `json:"owner_id"`
+	Name      string      `json:"name"`
+	Private   bool        `json:"private"`
+	Members   []uuid.UUID `json:"members,omitempty"`
+}
+
+func listToResponse(list database.List) listResponse {
+	return listResponse{
+		ID:        list.ID,
+		CreatedAt: list.CreatedAt,
+		UpdatedAt: list.UpdatedAt,
+		OwnerID:   list.OwnerID,
+		Name:      list.Name,
+		Private:   list.IsPrivate,
+	}
+}
+
+// listParams reads the name and privacy of a list create or update,
+// writing the error status itself when they are invalid.
+func listParams(w http.ResponseWriter, r *http.Request) (string, bool, bool) {
+	type paramaters struct {
+		Name    string `json:"name"`
+		Private bool   `json:"private"`
+	}
+
+	params := paramaters{}
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&params)
+	if err != nil {
+		log.Printf("error decoding list params: %v", err)
+		w.WriteHeader(400)
+		return "", false, false
+	}
+	name := strings.TrimSpace(params.Name)
+	if name == "" || utf8.RuneCountInString(name) > maxListNameLen {
+		w.WriteHeader(400)
+		return "", false, false
+	}
+	return name, params.Private, true
+}
+
+// viewableList loads the {listID} list if the viewer may see it: public
+// lists are open to everyone, private ones only to their owner. It writes
+// the error status itself.
+func (cfg *apiConfig) viewableList(w http.ResponseWriter, r *http.Request, viewer uuid.UUID, loggedIn bool) (database.List, bool) {
+	listID, err := uuid.Parse(r.PathValue("listID"))
+	if err != nil {
+		log.Printf("error converting ID to UUID %v", err)
+		w.WriteHeader(404)
+		return database.List{}, false
+	}
+	list, err := cfg.db.GetList(r.Context(), listID)
+	if errors.Is(err, sql.ErrNoRows) {
+		w.WriteHeader(404)
+		return database.List{}, false
+	}
+	if err != nil {
+		log.Printf("error getting list: %v", err)
+		w.WriteHeader(500)
+		return database.List{}, false
+	}
+	if list.IsPrivate && !(loggedIn && viewer == list.OwnerID) {
+		w.WriteHeader(404)
+		return database.List{}, false
+	}
+	return list, true
+}
+
+// ownedList authenticates the caller and loads the {listID} list, which
+// they must own. It writes the error status itself.
+func (cfg *apiConfig) ownedList(w http.ResponseWriter, r *http.Request) (database.List, bool) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting token: %v", err)
+		w.WriteHeader(401)
+		return database.List{}, false
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		log.Printf("token not valid: %v", err)
+		w.WriteHeader(401)
+		return database.List{}, false
+	}
+
+	list, ok := cfg.viewableList(w, r, userID, true)
+	if !ok {
+		return database.List{}, false
+	}
+	if list.OwnerID != userID {
+		w.WriteHeader(403)
+		return database.List{}, false
+	}
+	return list, true
+}
+
+func (cfg *apiConfig) createList(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting token: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		log.Printf("token not valid: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	name, private, ok := listParams(w, r)
+	if !ok {
+		return
+	}
+
+	count, err := cfg.db.CountListsForOwner(r.Context(), userID)
+	if err != nil {
+		log.Printf("error counting lists: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	if count >= maxListsPerUser {
+		w.WriteHeader(409)
+		return
+	}
+
+	now := time.Now()
+	list, err := cfg.db.CreateList(r.Context(), database.CreateListParams{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		OwnerID:   userID,
+		Name:      name,
+		IsPrivate: private,
+	})
+	if err != nil {
+		log.Printf("error creating list: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	val, err := json.Marshal(listToResponse(list))
+	if err != nil {
+		log.Printf("error marshalling list: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(201)
+	w.Write(val)
+}
+
+func (cfg *apiConfig) getLists(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting token: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		log.Printf("token not valid: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	lists, err := cfg.db.GetListsForOwner(r.Context(), userID)
+	if err != nil {
+		log.Printf("error getting lists: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	resp := []listResponse{}
+	for _, list := range lists {
+		resp = append(resp, listToResponse(list))
+	}
+	val, err := json.Marshal(resp)
+	if err != nil {
+		log.Printf("error marshalling lists: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(val)
+}
+
+func (cfg *apiConfig) getList(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	viewer, loggedIn := cfg.viewerID(r)
+	list, ok := cfg.viewableList(w, r, viewer, loggedIn)
+	if !ok {
+		return
+	}
+
+	members, err := cfg.db.GetListMembers(r.Context(), list.ID)
+	if err != nil {
+		log.Printf("error getting list members: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	resp := listToResponse(list)
+	resp.Members = []uuid.UUID{}
+	for _, m := range members {
+		resp.Members = append(resp.Members, m.UserID)
+	}
+	val, err := json.Marshal(resp)
+	if err != nil {
+		log.Printf("error marshalling list: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(val)
+}
+
+func (cfg *apiConfig) updateList(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	list, ok := cfg.ownedList(w, r)
+	if !ok {
+		return
+	}
+	name, private, ok := listParams(w, r)
+	if !ok {
+		return
+	}
+
+	list, err := cfg.db.UpdateList(r.Context(), database.UpdateListParams{
+		ID:        list.ID,
+		OwnerID:   list.OwnerID,
+		Name:      name,
+		IsPrivate: private,
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		log.Printf("error updating list: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	val, err := json.Marshal(listToResponse(list))
+	if err != nil {
+		log.Printf("error marshalling list: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(val)
+}
+
+func (cfg *apiConfig) deleteList(w http.ResponseWriter, r *http.Request) {
+	list, ok := cfg.ownedList(w, r)
+	if !ok {
+		return
+	}
+	_, err := cfg.db.DeleteList(r.Context(), database.DeleteListParams{
+		ID:      list.ID,
+		OwnerID: list.OwnerID,
+	})
+	if err != nil {
+		log.Printf("error deleting list: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) addListMember(w http.ResponseWriter, r *http.Request) {
+	list, ok := cfg.ownedList(w, r)
+	if !ok {
+		return
+	}
+
+	memberID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		log.Printf("error converting ID to UUID %v", err)
+		w.WriteHeader(404)
+		return
+	}
+
+	blocked, err := cfg.isBlocked(r.Context(), list.OwnerID, memberID)
+	if err != nil {
+		log.Printf("error checking block: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	if blocked {
+		w.WriteHeader(403)
+		return
+	}
+
+	count, err := cfg.db.CountListMembers(r.Context(), list.ID)
+	if err != nil {
+		log.Printf("error counting list members: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	if count >= maxListMembers {
+		w.WriteHeader(409)
+		return
+	}
+
+	err = cfg.db.AddListMember(r.Context(), database.AddListMemberParams{
+		ListID:    list.ID,
+		UserID:    memberID,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		log.Printf("error adding list member: %v", err)
+		w.WriteHeader(404)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) removeListMember(w http.ResponseWriter, r *http.Request) {
+	list, ok := cfg.ownedList(w, r)
+	if !ok {
+		return
+	}
+
+	memberID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		log.Printf("error converting ID to UUID %v", err)
+		w.WriteHeader(404)
+		return
+	}
+
+	err = cfg.db.RemoveListMember(r.Context(), database.RemoveListMemberParams{
+		ListID: list.ID,
+		UserID: memberID,
+	})
+	if err != nil {
+		log.Printf("error removing list member: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+// getListChirps is the list's timeline: chirps from its members, newest
+// first, filtered for the viewer like any other listing.
+func (cfg *apiConfig) getListChirps(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	viewer, loggedIn := cfg.viewerID(r)
+	list, ok := cfg.viewableList(w, r, viewer, loggedIn)
+	if !ok {
+		return
+	}
+
+	before, beforeID, limit, err := pageParams(r)
+	if err != nil {
+		log.Printf("invalid pagination params: %v", err)
+		w.WriteHeader(400)
+		return
+	}
+
+	data, err := cfg.db.GetListChirpsPage(r.Context(), database.GetListChirpsPageParams{
+		ListID:          list.ID,
+		BeforeCreatedAt: before,
+		BeforeID:        beforeID,
+		PageSize:        limit,
+	})
+	if err != nil {
+		log.Printf("error getting list chirps: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	filter, err := cfg.loadChirpFilter(r.Context(), viewer, loggedIn)
+	if err != nil {
+		log.Printf("error loading chirp filter: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	var visible []database.Chirp
+	for _, chirp := range data {
+		if filter.allows(chirp) {
+			visible = append(visible, chirp)
+		}
+	}
+
+	chirps, err := cfg.chirpResponses(r.Context(), viewer, visible)
+	if err != nil {
+		log.Printf("error building chirp responses: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	cfg.recordImpressions(r, viewer, loggedIn, chirps)
+	resp := chirpPage{Chirps: []chirpResponse{}}
+	resp.Chirps = append(resp.Chirps, chirps...)
+	// The cursor follows the rows we read, not the ones we kept, so a page
+	// of filtered chirps doesn't end the timeline early.
+	if len(data) == int(limit) {
+		last := data[len(data)-1]
+		resp.NextCursor = encodeCursor(last.CreatedAt, last.ID)
+	}
+
+	val, err := json.Marshal(resp)
+	if err != nil {
+		log.Printf("error marshalling list chirps: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(val)
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tristenkelly/chirpy/internal/auth"
+	"github.com/tristenkelly/chirpy/internal/database"
+	"github.com/tristenkelly/chirpy/internal/media"
+	"github.com/tristenkelly/chirpy/internal/storage"
+)
+
+// Stored media is content-addressed, so a key always names the same bytes
+// and can be cached forever.
+const mediaCacheControl = "public, max-age=31536000, immutable"
+
+// newMediaStore picks the storage backend from MEDIA_STORAGE: "local"
+// (the default) writes under MEDIA_DIR, "s3" talks to any S3-compatible
+// endpoint.
+func newMediaStore() (storage.Store, error) {
+	switch os.Getenv("MEDIA_STORAGE") {
+	case "", "local":
+		dir := os.Getenv("MEDIA_DIR")
+		if dir == "" {
+			dir = "media"
+		}
+		return storage.NewLocal(dir, "/media")
+	case "s3":
+		region := os.Getenv("S3_REGION")
+		if region == "" {
+			region = "us-east-1"
+		}
+		return &storage.S3{
+			Endpoint:     os.Getenv("S3_ENDPOINT"),
+			Region:       region,
+			Bucket:       os.Getenv("S3_BUCKET"),
+			AccessKey:    os.Getenv("S3_ACCESS_KEY"),
+			SecretKey:    os.Getenv("S3_SECRET_KEY"),
+			PublicURL:    os.Getenv("S3_PUBLIC_URL"),
+			CacheControl: mediaCacheControl,
+			Client:       &http.Client{Timeout: 30 * time.Second},
+		}, nil
+	default:
+		return nil, errors.New("unknown MEDIA_STORAGE " + os.Getenv("MEDIA_STORAGE"))
+	}
+}
+
+// deleteChirpMedia removes the media attached to a chirp, along with any
+// stored files, original or variant, that no other media still uses.
+func (cfg *apiConfig) deleteChirpMedia(ctx context.Context, chirpID uuid.UUID) {
+	files, err := cfg.db.GetMediaForChirps(ctx, []uuid.UUID{chirpID})
+	if err != nil {
+		log.Printf("error getting media for chirp %v: %v", chirpID, err)
+		return
+	}
+	if len(files) == 0 {
+		return
+	}
+	ids := make([]uuid.UUID, 0, len(files))
+	keys := map[string]bool{}
+	for _, f := range files {
+		ids = append(ids, f.ID)
+		keys[f.StorageKey] = true
+	}
+	variants, err := cfg.db.GetVariantsForMedia(ctx, ids)
+	if err != nil {
+		log.Printf("error getting media variants for chirp %v: %v", chirpID, err)
+		return
+	}
+	for _, v := range variants {
+		keys[v.StorageKey] = true
+	}
+
+	err = cfg.db.DeleteMediaForChirp(ctx, uuid.NullUUID{UUID: chirpID, Valid: true})
+	if err != nil {
+		log.Printf("error deleting media for chirp %v: %v", chirpID, err)
+		return
+	}
+	for key := range keys {
+		cfg.releaseMediaKey(ctx, key)
+	}
+}
+
+// releaseMediaKey deletes a stored file once no media or variant refers to
+// it. Identical uploads share a key, so one deletion mustn't take the file
+// away from the others. The check and the delete hold the key's lock, which
+// storeMediaKey also takes, so a concurrent upload of the same file either
+// lands first and keeps it or runs after and stores it again.
+func (cfg *apiConfig) releaseMediaKey(ctx context.Context, key string) {
+	tx, err := cfg.sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("error starting transaction for media key %s: %v", key, err)
+		return
+	}
+	defer tx.Rollback()
+	q := cfg.withTx(tx)
+
+	err = q.LockMediaKey(ctx, key)
+	if err != nil {
+		log.Printf("error locking media key %s: %v", key, err)
+		return
+	}
+	inUse, err := q.MediaKeyInUse(ctx, key)
+	if err != nil {
+		log.Printf("error checking media key %s: %v", key, err)
+		return
+	}
+	if inUse {
+		return
+	}
+	err = cfg.media.Delete(ctx, key)
+	if err != nil {
+		log.Printf("error deleting media %s: %v", key, err)
+	}
+}
+
+// storeMediaKey writes data under key and, in the same transaction, runs
+// save to record the row that refers to it. It holds the key's lock
+// throughout, so releaseMediaKey can't delete the file in between.
+func (cfg *apiConfig) storeMediaKey(ctx context.Context, key string, data []byte, contentType string, save func(q *database.Queries) error) error {
+	tx, err := cfg.sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	q := cfg.withTx(tx)
+
+	err = q.LockMediaKey(ctx, key)
+	if err != nil {
+		return err
+	}
+	err = cfg.media.Put(ctx, key, data, contentType)
+	if err != nil {
+		return err
+	}
+	err = save(q)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+var mediaKeyPattern = regexp.MustCompile(`^([0-9a-f-]+)(\.[a-z]+)$`)
+
+// serveMedia serves stored media by key. Responses are immutable: a key is
+// the hash of the file it names.
+func (cfg *apiConfig) serveMedia(w http.ResponseWriter, r *http.Request) {
+	match := mediaKeyPattern.FindStringSubmatch(r.PathValue("key"))
+	if match == nil {
+		w.WriteHeader(404)
+		return
+	}
+	contentType := ""
+	for ct, ext := range media.Extensions {
+		if ext == match[2] {
+			contentType = ct
+		}
+	}
+	if contentType == "" {
+		w.WriteHeader(404)
+		return
+	}
+
+	// Look the object up before answering a conditional request, so a
+	// deleted file stops answering 304 to clients that cached it.
+	rc, err := cfg.media.Get(r.Context(), match[0])
+	if errors.Is(err, storage.ErrNotFound) {
+		w.WriteHeader(404)
+		return
+	}
+	if err != nil {
+		log.Printf("error reading media %s: %v", match[0], err)
+		w.WriteHeader(500)
+		return
+	}
+	defer rc.Close()
+
+	etag := `"` + match[1] + `"`
+	w.Header().Set("Cache-Control", mediaCacheControl)
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(304)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(200)
+	io.Copy(w, rc)
+}
+
+func (cfg *apiConfig) uploadMedia(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting token: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		log.Printf("token not valid: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	// Leave a little room for the multipart framing around the file.
+	r.Body = http.MaxBytesReader(w, r.Body, cfg.maxMediaBytes+1<<16)
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			w.WriteHeader(413)
+			return
+		}
+		log.Printf("error reading upload: %v", err)
+		w.WriteHeader(400)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, cfg.maxMediaBytes+1))
+	if err != nil {
+		log.Printf("error reading upload: %v", err)
+		w.WriteHeader(400)
+		return
+	}
+	if int64(len(data)) > cfg.maxMediaBytes {
+		w.WriteHeader(413)
+		return
+	}
+
+	// Trust the bytes, not the client's Content-Type.
+	contentType := http.DetectContentType(data)
+	_, ok := media.Extensions[contentType]
+	if !ok {
+		w.WriteHeader(415)
+		return
+	}
+
+	data, err = media.StripMetadata(contentType, data)
+	if err != nil {
+		log.Printf("error stripping metadata: %v", err)
+		w.WriteHeader(400)
+		return
+	}
+
+	key := media.Key(data, contentType)
+	var m database.MediaFile
+	err = cfg.storeMediaKey(r.Context(), key, data, contentType, func(q *database.Queries) error {
+		var err error
+		m, err = q.CreateMedia(r.Context(), database.CreateMediaParams{
+			ID:          uuid.New(),
+			CreatedAt:   time.Now(),
+			UserID:      userID,
+			StorageKey:  key,
+			ContentType: contentType,
+			SizeBytes:   int64(len(data)),
+		})
+		return err
+	})
+	if err != nil {
+		log.Printf("error storing media: %v", err)
+		cfg.releaseMediaKey(r.Context(), key)
+		w.WriteHeader(500)
+		return
+	}
+	cfg.queueMedia(m.ID)
+
+	val, err := json.Marshal(mediaToResponse(cfg, m, nil))
+	if err != nil {
+		log.Printf("error marshalling media: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(201)
+	w.Write(val)
+}
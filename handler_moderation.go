@@ -0,0 +1,555 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tristenkelly/chirpy/internal/auth"
+	"github.com/tristenkelly/chirpy/internal/database"
+)
+
+var reportReasons = map[string]bool{
+	"spam":           true,
+	"harassment":     true,
+	"hate":           true,
+	"violence":       true,
+	"sexual":         true,
+	"self_harm":      true,
+	"misinformation": true,
+	"other":          true,
+}
+
+const (
+	reportOpen      = "open"
+	reportDismissed = "dismissed"
+	reportActioned  = "actioned"
+)
+
+type reportResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	ReporterID *uuid.UUID `json:"reporter_id"`
+	ChirpID    *uuid.UUID `json:"chirp_id"`
+	AuthorID   uuid.UUID  `json:"author_id"`
+	Reason     string     `json:"reason"`
+	Details    string     `json:"details"`
+	Status     string     `json:"status"`
+}
+
+func reportToResponse(report database.Report) reportResponse {
+	resp := reportResponse{
+		ID:        report.ID,
+		CreatedAt: report.CreatedAt,
+		UpdatedAt: report.UpdatedAt,
+		AuthorID:  report.AuthorID,
+		Reason:    report.Reason,
+		Details:   report.Details,
+		Status:    report.Status,
+	}
+	if report.ReporterID.Valid {
+		reporterID := report.ReporterID.UUID
+		resp.ReporterID = &reporterID
+	}
+	if report.ChirpID.Valid {
+		chirpID := report.ChirpID.UUID
+		resp.ChirpID = &chirpID
+	}
+	return resp
+}
+
+// isSuspended reports whether a moderator has suspended the user. Lookup
+// failures are logged and treated as not suspended.
+func (cfg *apiConfig) isSuspended(ctx context.Context, userID uuid.UUID) bool {
+	user, err := cfg.db.GetUserByID(ctx, userID)
+	if err != nil {
+		log.Printf("error getting user %v: %v", userID, err)
+		return false
+	}
+	return user.SuspendedAt.Valid
+}
+
+// moderator authenticates the caller and checks they are a moderator,
+// writing the error status itself when they aren't.
+func (cfg *apiConfig) moderator(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting token: %v", err)
+		w.WriteHeader(401)
+		return uuid.UUID{}, false
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		log.Printf("token not valid: %v", err)
+		w.WriteHeader(401)
+		return uuid.UUID{}, false
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		log.Printf("error getting moderator: %v", err)
+		w.WriteHeader(401)
+		return uuid.UUID{}, false
+	}
+	if !user.IsModerator || user.SuspendedAt.Valid {
+		w.WriteHeader(403)
+		return uuid.UUID{}, false
+	}
+	return user.ID, true
+}
+
+type moderationParams struct {
+	ReportID *uuid.UUID `json:"report_id"`
+	Note     string     `json:"note"`
+	// ContentWarning is only read when marking a chirp sensitive.
+	ContentWarning string `json:"content_warning"`
+}
+
+// decodeModerationParams reads the optional body sent with moderator
+// actions. An empty body is fine.
+func decodeModerationParams(r *http.Request) (moderationParams, error) {
+	params := moderationParams{}
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if errors.Is(err, io.EOF) {
+		return params, nil
+	}
+	return params, err
+}
+
+func (cfg *apiConfig) recordModerationAction(ctx context.Context, moderatorID uuid.UUID, action string, params moderationParams, chirpID, targetUserID uuid.NullUUID) error {
+	reportID := uuid.NullUUID{}
+	if params.ReportID != nil {
+		reportID = uuid.NullUUID{UUID: *params.ReportID, Valid: true}
+	}
+	_, err := cfg.db.CreateModerationAction(ctx, database.CreateModerationActionParams{
+		ID:           uuid.New(),
+		CreatedAt:    time.Now(),
+		ModeratorID:  moderatorID,
+		Action:       action,
+		ReportID:     reportID,
+		ChirpID:      chirpID,
+		TargetUserID: targetUserID,
+		Note:         params.Note,
+	})
+	if err != nil {
+		return err
+	}
+	if reportID.Valid {
+		status := reportActioned
+		if action == "dismiss" {
+			status = reportDismissed
+		}
+		return cfg.db.SetReportStatus(ctx, database.SetReportStatusParams{
+			ID:        reportID.UUID,
+			Status:    status,
+			UpdatedAt: time.Now(),
+		})
+	}
+	return nil
+}
+
+func (cfg *apiConfig) reportChirp(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting token: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		log.Printf("token not valid: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	chirpUUID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		log.Printf("error converting ID to UUID %v", err)
+		w.WriteHeader(404)
+		return
+	}
+
+	chirp, err := cfg.db.GetChirp(r.Context(), chirpUUID)
+	if err != nil {
+		log.Printf("error getting chirp %v", err)
+		w.WriteHeader(404)
+		return
+	}
+	filter, err := cfg.loadChirpFilter(r.Context(), userID, true)
+	if err != nil {
+		log.Printf("error loading chirp filter: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	if !filter.canSee(chirp) {
+		w.WriteHeader(404)
+		return
+	}
+
+	type paramaters struct {
+		Reason  string `json:"reason"`
+		Details string `json:"details"`
+	}
+
+	params := paramaters{}
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&params)
+	if err != nil {
+		log.Printf("error decoding report params: %v", err)
+		w.WriteHeader(400)
+		return
+	}
+	if !reportReasons[params.Reason] {
+		w.WriteHeader(400)
+		return
+	}
+
+	now := time.Now()
+	report, err := cfg.db.CreateReport(r.Context(), database.CreateReportParams{
+		ID:         uuid.New(),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		ReporterID: uuid.NullUUID{UUID: userID, Valid: true},
+		ChirpID:    uuid.NullUUID{UUID: chirp.ID, Valid: true},
+		AuthorID:   chirp.UserID,
+		Reason:     params.Reason,
+		Details:    params.Details,
+	})
+	if err != nil {
+		log.Printf("error creating report: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	val, err := json.Marshal(reportToResponse(report))
+	if err != nil {
+		log.Printf("error marshalling report: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(201)
+	w.Write(val)
+}
+
+func (cfg *apiConfig) getReports(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if _, ok := cfg.moderator(w, r); !ok {
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = reportOpen
+	}
+
+	reports, err := cfg.db.GetReportsByStatus(r.Context(), status)
+	if err != nil {
+		log.Printf("error getting reports: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	type queuedReport struct {
+		reportResponse
+		ChirpBody *string `json:"chirp_body"`
+	}
+
+	resp := []queuedReport{}
+	for _, report := range reports {
+		queued := queuedReport{reportResponse: reportToResponse(report)}
+		if report.ChirpID.Valid {
+			chirp, err := cfg.db.GetChirp(r.Context(), report.ChirpID.UUID)
+			if err == nil {
+				queued.ChirpBody = &chirp.Body
+			}
+		}
+		resp = append(resp, queued)
+	}
+
+	val, err := json.Marshal(resp)
+	if err != nil {
+		log.Printf("error marshalling reports: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(val)
+}
+
+func (cfg *apiConfig) dismissReport(w http.ResponseWriter, r *http.Request) {
+	moderatorID, ok := cfg.moderator(w, r)
+	if !ok {
+		return
+	}
+
+	reportID, err := uuid.Parse(r.PathValue("reportID"))
+	if err != nil {
+		log.Printf("error converting ID to UUID %v", err)
+		w.WriteHeader(404)
+		return
+	}
+	report, err := cfg.db.GetReport(r.Context(), reportID)
+	if err != nil {
+		log.Printf("error getting report: %v", err)
+		w.WriteHeader(404)
+		return
+	}
+
+	params, err := decodeModerationParams(r)
+	if err != nil {
+		log.Printf("error decoding moderation params: %v", err)
+		w.WriteHeader(400)
+		return
+	}
+	params.ReportID = &report.ID
+
+	err = cfg.recordModerationAction(r.Context(), moderatorID, "dismiss", params, report.ChirpID, uuid.NullUUID{UUID: report.AuthorID, Valid: true})
+	if err != nil {
+		log.Printf("error recording moderation action: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+// moderateChirp runs the shared part of the hide, unhide and remove actions:
+// moderator check, chirp lookup and body decoding.
+func (cfg *apiConfig) moderateChirp(w http.ResponseWriter, r *http.Request) (uuid.UUID, database.Chirp, moderationParams, bool) {
+	moderatorID, ok := cfg.moderator(w, r)
+	if !ok {
+		return uuid.UUID{}, database.Chirp{}, moderationParams{}, false
+	}
+
+	chirpUUID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		log.Printf("error converting ID to UUID %v", err)
+		w.WriteHeader(404)
+		return uuid.UUID{}, database.Chirp{}, moderationParams{}, false
+	}
+	chirp, err := cfg.db.GetChirp(r.Context(), chirpUUID)
+	if err != nil {
+		log.Printf("error getting chirp %v", err)
+		w.WriteHeader(404)
+		return uuid.UUID{}, database.Chirp{}, moderationParams{}, false
+	}
+
+	params, err := decodeModerationParams(r)
+	if err != nil {
+		log.Printf("error decoding moderation params: %v", err)
+		w.WriteHeader(400)
+		return uuid.UUID{}, database.Chirp{}, moderationParams{}, false
+	}
+	return moderatorID, chirp, params, true
+}
+
+func (cfg *apiConfig) setChirpHidden(w http.ResponseWriter, r *http.Request, hidden bool) {
+	moderatorID, chirp, params, ok := cfg.moderateChirp(w, r)
+	if !ok {
+		return
+	}
+
+	action := "unhide"
+	hiddenAt := sql.NullTime{}
+	if hidden {
+		action = "hide"
+		hiddenAt = sql.NullTime{Time: time.Now(), Valid: true}
+	}
+
+	err := cfg.db.SetChirpHidden(r.Context(), database.SetChirpHiddenParams{
+		ID:        chirp.ID,
+		HiddenAt:  hiddenAt,
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		log.Printf("error hiding chirp: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	chirpID := uuid.NullUUID{UUID: chirp.ID, Valid: true}
+	err = cfg.recordModerationAction(r.Context(), moderatorID, action, params, chirpID, uuid.NullUUID{UUID: chirp.UserID, Valid: true})
+	if err != nil {
+		log.Printf("error recording moderation action: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	if hidden {
+		err = cfg.db.ResolveReportsForChirp(r.Context(), database.ResolveReportsForChirpParams{
+			ChirpID:   chirpID,
+			Status:    reportActioned,
+			UpdatedAt: time.Now(),
+		})
+		if err != nil {
+			log.Printf("error resolving reports: %v", err)
+		}
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) hideChirp(w http.ResponseWriter, r *http.Request) {
+	cfg.setChirpHidden(w, r, true)
+}
+
+func (cfg *apiConfig) unhideChirp(w http.ResponseWriter, r *http.Request) {
+	cfg.setChirpHidden(w, r, false)
+}
+
+func (cfg *apiConfig) removeChirp(w http.ResponseWriter, r *http.Request) {
+	moderatorID, chirp, params, ok := cfg.moderateChirp(w, r)
+	if !ok {
+		return
+	}
+
+	chirpID := uuid.NullUUID{UUID: chirp.ID, Valid: true}
+	// Resolve and log first: the reports keep their row once the chirp is
+	// gone, but lose the link to it.
+	err := cfg.db.ResolveReportsForChirp(r.Context(), database.ResolveReportsForChirpParams{
+		ChirpID:   chirpID,
+		Status:    reportActioned,
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		log.Printf("error resolving reports: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	err = cfg.recordModerationAction(r.Context(), moderatorID, "remove", params, chirpID, uuid.NullUUID{UUID: chirp.UserID, Valid: true})
+	if err != nil {
+		log.Printf("error recording moderation action: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	cfg.deleteChirpMedia(r.Context(), chirp.ID)
+	err = cfg.db.RemoveChirp(r.Context(), chirp.ID)
+	if err != nil {
+		log.Printf("error removing chirp: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	cfg.recordDeletedChirp(r.Context(), chirp.ID)
+	cfg.federateDelete(r.Context(), chirp)
+	cfg.emitChirpDeleted(r.Context(), chirp)
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) setUserSuspended(w http.ResponseWriter, r *http.Request, suspended bool) {
+	moderatorID, ok := cfg.moderator(w, r)
+	if !ok {
+		return
+	}
+
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		log.Printf("error converting ID to UUID %v", err)
+		w.WriteHeader(404)
+		return
+	}
+	if _, err := cfg.db.GetUserByID(r.Context(), userID); err != nil {
+		log.Printf("error getting user: %v", err)
+		w.WriteHeader(404)
+		return
+	}
+
+	params, err := decodeModerationParams(r)
+	if err != nil {
+		log.Printf("error decoding moderation params: %v", err)
+		w.WriteHeader(400)
+		return
+	}
+
+	action := "unsuspend"
+	suspendedAt := sql.NullTime{}
+	if suspended {
+		action = "suspend"
+		suspendedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	}
+
+	err = cfg.db.SetUserSuspended(r.Context(), database.SetUserSuspendedParams{
+		ID:          userID,
+		SuspendedAt: suspendedAt,
+		UpdatedAt:   time.Now(),
+	})
+	if err != nil {
+		log.Printf("error suspending user: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	err = cfg.recordModerationAction(r.Context(), moderatorID, action, params, uuid.NullUUID{}, uuid.NullUUID{UUID: userID, Valid: true})
+	if err != nil {
+		log.Printf("error recording moderation action: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) suspendUser(w http.ResponseWriter, r *http.Request) {
+	cfg.setUserSuspended(w, r, true)
+}
+
+func (cfg *apiConfig) unsuspendUser(w http.ResponseWriter, r *http.Request) {
+	cfg.setUserSuspended(w, r, false)
+}
+
+func (cfg *apiConfig) getModerationActions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if _, ok := cfg.moderator(w, r); !ok {
+		return
+	}
+
+	actions, err := cfg.db.GetModerationActions(r.Context())
+	if err != nil {
+		log.Printf("error getting moderation actions: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	type actionResponse struct {
+		ID           uuid.UUID     `json:"id"`
+		CreatedAt    time.Time     `json:"created_at"`
+		ModeratorID  uuid.UUID     `json:"moderator_id"`
+		Action       string        `json:"action"`
+		ReportID     uuid.NullUUID `json:"report_id"`
+		ChirpID      uuid.NullUUID `json:"chirp_id"`
+		TargetUserID uuid.NullUUID `json:"target_user_id"`
+		Note         string        `json:"note"`
+	}
+
+	resp := []actionResponse{}
+	for _, a := range actions {
+		resp = append(resp, actionResponse{
+			ID:           a.ID,
+			CreatedAt:    a.CreatedAt,
+			ModeratorID:  a.ModeratorID,
+			Action:       a.Action,
+			ReportID:     a.ReportID,
+			ChirpID:      a.ChirpID,
+			TargetUserID: a.TargetUserID,
+			Note:         a.Note,
+		})
+	}
+
+	val, err := json.Marshal(resp)
+	if err != nil {
+		log.Printf("error marshalling moderation actions: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(val)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tristenkelly/chirpy/internal/auth"
+	"github.com/tristenkelly/chirpy/internal/database"
+)
+
+const notificationPollClosed = "poll_closed"
+
+type notificationResponse struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	Kind      string     `json:"kind"`
+	ChirpID   *uuid.UUID `json:"chirp_id,omitempty"`
+	Read      bool       `json:"read"`
+}
+
+// notify records a notification for a user. Failures are logged rather
+// than returned: a missed notification shouldn't fail the action behind it.
+func (cfg *apiConfig) notify(ctx context.Context, userID uuid.UUID, kind string, chirpID uuid.NullUUID) {
+	err := cfg.db.CreateNotification(ctx, database.CreateNotificationParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UserID:    userID,
+		Kind:      kind,
+		ChirpID:   chirpID,
+	})
+	if err != nil {
+		log.Printf("error creating %s notification for %v: %v", kind, userID, err)
+	}
+}
+
+func (cfg *apiConfig) getNotifications(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting token: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		log.Printf("token not valid: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	before, beforeID, limit, err := pageParams(r)
+	if err != nil {
+		log.Printf("invalid pagination params: %v", err)
+		w.WriteHeader(400)
+		return
+	}
+
+	data, err := cfg.db.GetNotificationsPage(r.Context(), database.GetNotificationsPageParams{
+		UserID:          userID,
+		BeforeCreatedAt: before,
+		BeforeID:        beforeID,
+		PageSize:        limit,
+	})
+	if err != nil {
+		log.Printf("error getting notifications: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	type notificationPage struct {
+		Notifications []notificationResponse `json:"notifications"`
+		NextCursor    string                 `json:"next_cursor,omitempty"`
+	}
+
+	resp := notificationPage{Notifications: []notificationResponse{}}
+	for _, n := range data {
+		item := notificationResponse{
+			ID:        n.ID,
+			CreatedAt: n.CreatedAt,
+			Kind:      n.Kind,
+			Read:      n.ReadAt.Valid,
+		}
+		if n.ChirpID.Valid {
+			item.ChirpID = &n.ChirpID.UUID
+		}
+		resp.Notifications = append(resp.Notifications, item)
+	}
+	if len(data) == int(limit) {
+		last := data[len(data)-1]
+		resp.NextCursor = encodeCursor(last.CreatedAt, last.ID)
+	}
+
+	val, err := json.Marshal(resp)
+	if err != nil {
+		log.Printf("error marshalling notifications: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(val)
+}
+
+func (cfg *apiConfig) markNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting token: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		log.Printf("token not valid: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	err = cfg.db.MarkNotificationsRead(r.Context(), database.MarkNotificationsReadParams{
+		UserID: userID,
+		ReadAt: sql.NullTime{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		log.Printf("error marking notifications read: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(204)
+}
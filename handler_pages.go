@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/tristenkelly/chirpy/internal/database"
+)
+
+const (
+	pageDescriptionLength = 200
+	embedDefaultWidth     = 550
+	embedMinWidth         = 220
+	embedCacheAge         = 3600
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+var pageTemplates = template.Must(template.ParseFS(templateFS, "templates/*.html"))
+
+// chirpPageData is the data behind chirp.html and embed.html.
+type chirpPageData struct {
+	Chirp       chirpResponse
+	URL         string
+	Title       string
+	Description string
+	Image       string
+	Media       []string
+	Collapsed   bool
+	OEmbedURL   string
+	ActivityURL string
+	Width       int
+}
+
+type gonePage struct {
+	Title   string
+	Message string
+}
+
+// pageChirp loads a chirp for a public page. Chirps that were deleted,
+// hidden by a moderator or written by a suspended user are gone (410);
+// ones that never existed or aren't public or unlisted are not found (404).
+func (cfg *apiConfig) pageChirp(ctx context.Context, chirpID uuid.UUID) (chirpResponse, int, error) {
+	chirp, err := cfg.db.GetChirp(ctx, chirpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		deleted, err := cfg.db.IsChirpDeleted(ctx, chirpID)
+		if err != nil {
+			return chirpResponse{}, 500, err
+		}
+		if deleted {
+			return chirpResponse{}, 410, nil
+		}
+		return chirpResponse{}, 404, nil
+	}
+	if err != nil {
+		return chirpResponse{}, 500, err
+	}
+	if chirp.HiddenAt.Valid {
+		return chirpResponse{}, 410, nil
+	}
+	author, err := cfg.db.GetUserByID(ctx, chirp.UserID)
+	if err != nil {
+		return chirpResponse{}, 500, err
+	}
+	if author.SuspendedAt.Valid {
+		return chirpResponse{}, 410, nil
+	}
+
+	filter, err := cfg.loadChirpFilter(ctx, uuid.Nil, false)
+	if err != nil {
+		return chirpResponse{}, 500, err
+	}
+	if !filter.canSee(chirp) {
+		return chirpResponse{}, 404, nil
+	}
+	resp, err := cfg.chirpResponses(ctx, uuid.Nil, []database.Chirp{chirp})
+	if err != nil {
+		return chirpResponse{}, 500, err
+	}
+	return resp[0], 200, nil
+}
+
+// getChirpPage renders /c/{chirpID} as HTML with OpenGraph tags, so shared
+// links unfurl in chat apps and social sites.
+func (cfg *apiConfig) getChirpPage(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		cfg.renderGone(w, 404)
+		return
+	}
+	chirp, status, err := cfg.pageChirp(r.Context(), chirpID)
+	if err != nil {
+		log.Printf("error loading chirp page: %v", err)
+	}
+	if status != 200 {
+		cfg.renderGone(w, status)
+		return
+	}
+
+	base := cfg.baseURL(r)
+	page := cfg.newChirpPage(base, chirp)
+	page.OEmbedURL = base + "/oembed?" + url.Values{"url": {page.URL}}.Encode()
+	if cfg.publicURL != "" {
+		page.ActivityURL = cfg.noteURL(chirp.ID)
+	}
+
+	buf := bytes.Buffer{}
+	if err := pageTemplates.ExecuteTemplate(&buf, "chirp.html", page); err != nil {
+		log.Printf("error rendering chirp page: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "public, max-age=60")
+	w.WriteHeader(200)
+	w.Write(buf.Bytes())
+	cfg.recordImpressions(r, uuid.Nil, false, []chirpResponse{chirp})
+}
+
+func (cfg *apiConfig) newChirpPage(base string, chirp chirpResponse) chirpPageData {
+	page := chirpPageData{
+		Chirp:     chirp,
+		URL:       chirpPageURL(base, chirp.ID),
+		Title:     "Chirp by " + chirp.UserID.String(),
+		Collapsed: chirp.Collapsed,
+	}
+	// A content warning stands in for the text in previews, and sensitive
+	// images are left out of them.
+	if chirp.ContentWarning != "" {
+		page.Description = "CW: " + chirp.ContentWarning
+	} else {
+		page.Description = truncateRunes(chirp.Body, pageDescriptionLength)
+	}
+	for _, m := range chirp.Media {
+		mediaURL := m.URL
+		if strings.HasPrefix(mediaURL, "/") {
+			mediaURL = base + mediaURL
+		}
+		page.Media = append(page.Media, mediaURL)
+		if page.Image == "" && !chirp.Collapsed && strings.HasPrefix(m.ContentType, "image/") {
+			page.Image = mediaURL
+		}
+	}
+	return page
+}
+
+func (cfg *apiConfig) renderGone(w http.ResponseWriter, status int) {
+	page := gonePage{Title: "Chirp not found", Message: "This chirp doesn't exist or isn't public."}
+	switch status {
+	case 410:
+		page = gonePage{Title: "Chirp removed", Message: "This chirp was deleted or is no longer available."}
+	case 500:
+		page = gonePage{Title: "Something went wrong", Message: "Please try again later."}
+	}
+	buf := bytes.Buffer{}
+	if err := pageTemplates.ExecuteTemplate(&buf, "gone.html", page); err != nil {
+		log.Printf("error rendering error page: %v", err)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+// oembed implements the oEmbed provider endpoint for chirp pages. Only
+// JSON is offered; other formats get 501 as the spec asks.
+func (cfg *apiConfig) oembed(w http.ResponseWriter, r *http.Request) {
+	type oembedResponse struct {
+		Type         string `json:"type"`
+		Version      string `json:"version"`
+		Title        string `json:"title"`
+		AuthorName   string `json:"author_name"`
+		ProviderName string `json:"provider_name"`
+		ProviderURL  string `json:"provider_url"`
+		CacheAge     int    `json:"cache_age"`
+		HTML         string `json:"html"`
+		Width        int    `json:"width"`
+		Height       *int   `json:"height"`
+	}
+
+	query := r.URL.Query()
+	if format := query.Get("format"); format != "" && format != "json" {
+		w.WriteHeader(501)
+		return
+	}
+	width := embedDefaultWidth
+	if s := query.Get("maxwidth"); s != "" {
+		maxWidth, err := strconv.Atoi(s)
+		if err != nil {
+			w.WriteHeader(400)
+			return
+		}
+		width = min(width, max(maxWidth, embedMinWidth))
+	}
+
+	base := cfg.baseURL(r)
+	rest, ok := strings.CutPrefix(query.Get("url"), base+"/c/")
+	if !ok {
+		w.WriteHeader(404)
+		return
+	}
+	chirpID, err := uuid.Parse(rest)
+	if err != nil {
+		w.WriteHeader(404)
+		return
+	}
+	chirp, status, err := cfg.pageChirp(r.Context(), chirpID)
+	if err != nil {
+		log.Printf("error loading chirp for oembed: %v", err)
+	}
+	if status != 200 {
+		// oEmbed only knows 404 for URLs with nothing to embed.
+		if status == 410 {
+			status = 404
+		}
+		w.WriteHeader(status)
+		return
+	}
+
+	page := cfg.newChirpPage(base, chirp)
+	page.Width = width
+	buf := bytes.Buffer{}
+	if err := pageTemplates.ExecuteTemplate(&buf, "embed.html", page); err != nil {
+		log.Printf("error rendering embed: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	val, err := json.Marshal(oembedResponse{
+		Type:         "rich",
+		Version:      "1.0",
+		Title:        page.Description,
+		AuthorName:   chirp.UserID.String(),
+		ProviderName: "Chirpy",
+		ProviderURL:  base,
+		CacheAge:     embedCacheAge,
+		HTML:         buf.String(),
+		Width:        width,
+	})
+	if err != nil {
+		log.Printf("error marshalling oembed: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	w.Write(val)
+}
+
+// recordDeletedChirp remembers a deleted chirp's ID so its page can say it
+// is gone rather than never existed.
+func (cfg *apiConfig) recordDeletedChirp(ctx context.Context, chirpID uuid.UUID) {
+	err := cfg.db.RecordDeletedChirp(ctx, database.RecordDeletedChirpParams{
+		ID:        chirpID,
+		DeletedAt: time.Now(),
+	})
+	if err != nil {
+		log.Printf("error recording deleted chirp %v: %v", chirpID, err)
+	}
+}
+
+func chirpPageURL(base string, chirpID uuid.UUID) string {
+	return base + "/c/" + chirpID.String()
+}
+
+// truncateRunes shortens s to at most n runes, marking the cut with an
+// ellipsis.
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	return strings.TrimSpace(string(runes[:n-1])) + "…"
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tristenkelly/chirpy/internal/auth"
+	"github.com/tristenkelly/chirpy/internal/database"
+)
+
+const maxPinnedChirps = 3
+
+// pinChirp pins one of the caller's own chirps to their profile. Pinning
+// is idempotent; a fourth pin is refused with 409 until another is removed.
+func (cfg *apiConfig) pinChirp(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting token: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		log.Printf("token not valid: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		log.Printf("error converting ID to UUID %v", err)
+		w.WriteHeader(404)
+		return
+	}
+
+	chirp, err := cfg.db.GetChirp(r.Context(), chirpID)
+	if err != nil {
+		w.WriteHeader(404)
+		return
+	}
+	if chirp.UserID != userID {
+		w.WriteHeader(403)
+		return
+	}
+
+	// The limit is checked inside the INSERT so two concurrent pins can't
+	// both slip under it.
+	inserted, err := cfg.db.PinChirp(r.Context(), database.PinChirpParams{
+		UserID:    userID,
+		ChirpID:   chirpID,
+		CreatedAt: time.Now(),
+		MaxPins:   maxPinnedChirps,
+	})
+	if err != nil {
+		log.Printf("error pinning chirp: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	if inserted == 0 {
+		pinned, err := cfg.db.GetPinnedChirpIDs(r.Context(), []uuid.UUID{chirpID})
+		if err != nil {
+			log.Printf("error getting pinned chirps: %v", err)
+			w.WriteHeader(500)
+			return
+		}
+		if len(pinned) == 0 {
+			w.WriteHeader(409)
+			return
+		}
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) unpinChirp(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting token: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		log.Printf("token not valid: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		log.Printf("error converting ID to UUID %v", err)
+		w.WriteHeader(404)
+		return
+	}
+
+	err = cfg.db.UnpinChirp(r.Context(), database.UnpinChirpParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		log.Printf("error unpinning chirp: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+// withPinned moves an author's pinned chirps to the front of their
+// timeline, newest pin first. Pins the viewer isn't allowed to see are
+// dropped like any other chirp.
+func (cfg *apiConfig) withPinned(ctx context.Context, viewer uuid.UUID, filter chirpFilter, authorID uuid.UUID, chirps []chirpResponse) ([]chirpResponse, error) {
+	data, err := cfg.db.GetPinnedChirps(ctx, authorID)
+	if err != nil {
+		return nil, err
+	}
+	var visible []database.Chirp
+	for _, chirp := range data {
+		if filter.allows(chirp) {
+			visible = append(visible, chirp)
+		}
+	}
+	pinned, err := cfg.chirpResponses(ctx, viewer, visible)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := pinned
+	for _, chirp := range chirps {
+		if !chirp.Pinned {
+			resp = append(resp, chirp)
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tristenkelly/chirpy/internal/auth"
+	"github.com/tristenkelly/chirpy/internal/chirplen"
+	"github.com/tristenkelly/chirpy/internal/database"
+)
+
+const (
+	minPollOptions     = 2
+	maxPollOptions     = 4
+	maxPollOptionLen   = 50
+	minPollDuration    = 5 * time.Minute
+	maxPollDuration    = 7 * 24 * time.Hour
+	pollCloserInterval = 30 * time.Second
+)
+
+// pollParams is the poll part of a POST /api/chirps request.
+type pollParams struct {
+	Options   []string  `json:"options"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Multiple  bool      `json:"multiple"`
+}
+
+type pollResponse struct {
+	Options   []pollOptionResponse `json:"options"`
+	Multiple  bool                 `json:"multiple"`
+	ExpiresAt time.Time            `json:"expires_at"`
+	Closed    bool                 `json:"closed"`
+	Voted     bool                 `json:"voted"`
+	OwnVotes  []int32              `json:"own_votes,omitempty"`
+}
+
+type pollOptionResponse struct {
+	Title string `json:"title"`
+	// Votes is left out until the viewer has voted or the poll has closed,
+	// so early results don't sway later voters.
+	Votes *int64 `json:"votes,omitempty"`
+}
+
+// validatePoll checks a poll before its chirp is created, running the
+// option titles through the content filter. It returns a message for the
+// client when the poll is invalid.
+func (cfg *apiConfig) validatePoll(p *pollParams, now time.Time) string {
+	if len(p.Options) < minPollOptions || len(p.Options) > maxPollOptions {
+		return "A poll needs between 2 and 4 options"
+	}
+	seen := map[string]bool{}
+	for i, option := range p.Options {
+		option = strings.TrimSpace(option)
+		if option == "" {
+			return "Poll options can't be empty"
+		}
+		if chirplen.Count(option, cfg.urlWeight) > maxPollOptionLen {
+			return "Poll option is too long"
+		}
+		if seen[strings.ToLower(option)] {
+			return "Poll options must be different"
+		}
+		seen[strings.ToLower(option)] = true
+
+		filtered := cfg.filter.Apply(option)
+		if filtered.Rejected {
+			return "Poll option rejected: " + strings.Join(filtered.Reasons, ", ")
+		}
+		p.Options[i] = filtered.Text
+	}
+	duration := p.ExpiresAt.Sub(now)
+	if duration < minPollDuration || duration > maxPollDuration {
+		return "A poll must run for between 5 minutes and 7 days"
+	}
+	return ""
+}
+
+// createPoll adds a poll to a chirp using q, so it can go in the same
+// transaction as the chirp.
+func createPoll(ctx context.Context, q *database.Queries, chirpID uuid.UUID, p pollParams) error {
+	_, err := q.CreatePoll(ctx, database.CreatePollParams{
+		ChirpID:   chirpID,
+		CreatedAt: time.Now(),
+		Multiple:  p.Multiple,
+		ClosesAt:  p.ExpiresAt.Local(),
+	})
+	if err != nil {
+		return err
+	}
+	for i, option := range p.Options {
+		err := q.CreatePollOption(ctx, database.CreatePollOptionParams{
+			ChirpID:  chirpID,
+			Position: int32(i),
+			Title:    option,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pollResponses loads the polls attached to a set of chirps, as seen by
+// viewer (uuid.Nil when logged out).
+func (cfg *apiConfig) pollResponses(ctx context.Context, viewer uuid.UUID, chirpIDs []uuid.UUID) (map[uuid.UUID]*pollResponse, error) {
+	// Whether a poll has closed is worked out by the database, which holds
+	// closes_at as local wall-clock time.
+	polls, err := cfg.db.GetPollsForChirps(ctx, database.GetPollsForChirpsParams{
+		Now:      time.Now(),
+		ChirpIds: chirpIDs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp := map[uuid.UUID]*pollResponse{}
+	if len(polls) == 0 {
+		return resp, nil
+	}
+	ids := make([]uuid.UUID, 0, len(polls))
+	for _, p := range polls {
+		ids = append(ids, p.ChirpID)
+	}
+
+	options, err := cfg.db.GetPollOptionsForChirps(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	tallies, err := cfg.db.GetPollTallies(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	votes := map[uuid.UUID][]int32{}
+	if viewer != uuid.Nil {
+		rows, err := cfg.db.GetPollVotesByUser(ctx, database.GetPollVotesByUserParams{
+			UserID:   viewer,
+			ChirpIds: ids,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			votes[row.ChirpID] = row.Choices
+		}
+	}
+	counts := map[uuid.UUID]map[int32]int64{}
+	for _, t := range tallies {
+		if counts[t.ChirpID] == nil {
+			counts[t.ChirpID] = map[int32]int64{}
+		}
+		counts[t.ChirpID][t.Position] = t.Votes
+	}
+
+	for _, p := range polls {
+		ownVotes, voted := votes[p.ChirpID]
+		resp[p.ChirpID] = &pollResponse{
+			Options:   []pollOptionResponse{},
+			Multiple:  p.Multiple,
+			ExpiresAt: fromTimestamp(p.ClosesAt),
+			Closed:    p.Closed,
+			Voted:     voted,
+			OwnVotes:  ownVotes,
+		}
+	}
+	for _, o := range options {
+		poll := resp[o.ChirpID]
+		option := pollOptionResponse{Title: o.Title}
+		if poll.Voted || poll.Closed {
+			n := counts[o.ChirpID][o.Position]
+			option.Votes = &n
+		}
+		poll.Options = append(poll.Options, option)
+	}
+	return resp, nil
+}
+
+func (cfg *apiConfig) votePoll(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	type paramaters struct {
+		Choices []int32 `json:"choices"`
+	}
+
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting token: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		log.Printf("token not valid: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	if cfg.isSuspended(r.Context(), userID) {
+		w.WriteHeader(403)
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		log.Printf("error converting ID to UUID %v", err)
+		w.WriteHeader(404)
+		return
+	}
+
+	chirp, err := cfg.db.GetChirp(r.Context(), chirpID)
+	if err != nil {
+		w.WriteHeader(404)
+		return
+	}
+	filter, err := cfg.loadChirpFilter(r.Context(), userID, true)
+	if err != nil {
+		log.Printf("error loading chirp filter: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	blocked, err := cfg.isBlocked(r.Context(), userID, chirp.UserID)
+	if err != nil {
+		log.Printf("error checking block: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	if !filter.canSee(chirp) || blocked {
+		w.WriteHeader(404)
+		return
+	}
+
+	poll, err := cfg.db.GetPoll(r.Context(), database.GetPollParams{
+		Now:     time.Now(),
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		w.WriteHeader(404)
+		return
+	}
+
+	params := paramaters{}
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&params)
+	if err != nil {
+		log.Printf("error decoding poll vote: %v", err)
+		w.WriteHeader(400)
+		return
+	}
+
+	respError := errorResponse{}
+	options, err := cfg.db.GetPollOptionsForChirps(r.Context(), []uuid.UUID{chirpID})
+	if err != nil {
+		log.Printf("error getting poll options: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	slices.Sort(params.Choices)
+	switch {
+	case poll.Closed:
+		respError.Error = "Poll is closed"
+	case len(params.Choices) == 0:
+		respError.Error = "Pick at least one option"
+	case len(params.Choices) > 1 && !poll.Multiple:
+		respError.Error = "Pick only one option"
+	case len(slices.Compact(slices.Clone(params.Choices))) != len(params.Choices):
+		respError.Error = "Options can only be picked once"
+	case params.Choices[0] < 0 || int(params.Choices[len(params.Choices)-1]) >= len(options):
+		respError.Error = "Invalid option"
+	}
+	if respError.Error != "" {
+		val, _ := json.Marshal(respError)
+		w.WriteHeader(400)
+		w.Write(val)
+		return
+	}
+
+	inserted, err := cfg.db.CreatePollVote(r.Context(), database.CreatePollVoteParams{
+		ChirpID:   chirpID,
+		UserID:    userID,
+		CreatedAt: time.Now(),
+		Choices:   params.Choices,
+	})
+	if err != nil {
+		log.Printf("error creating poll vote: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	if inserted == 0 {
+		val, _ := json.Marshal(errorResponse{Error: "Already voted"})
+		w.WriteHeader(409)
+		w.Write(val)
+		return
+	}
+	cfg.recordEngagement(chirp, userID.String())
+
+	polls, err := cfg.pollResponses(r.Context(), userID, []uuid.UUID{chirpID})
+	if err != nil {
+		log.Printf("error loading poll: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	val, err := json.Marshal(polls[chirpID])
+	if err != nil {
+		log.Printf("error marshalling poll: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(201)
+	w.Write(val)
+}
+
+// runPollCloser marks polls closed once they expire and lets their authors
+// know. Each poll is closed by a single UPDATE, so the author is notified
+// once even with several instances running.
+func (cfg *apiConfig) runPollCloser(ctx context.Context) {
+	ticker := time.NewTicker(pollCloserInterval)
+	defer ticker.Stop()
+	for {
+		closed, err := cfg.db.ClosePolls(ctx, time.Now())
+		if err != nil {
+			log.Printf("error closing polls: %v", err)
+		}
+		for _, p := range closed {
+			cfg.notify(ctx, p.UserID, notificationPollClosed, uuid.NullUUID{UUID: p.ChirpID, Valid: true})
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
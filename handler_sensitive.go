@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/tristenkelly/chirpy/internal/auth"
+	"github.com/tristenkelly/chirpy/internal/database"
+	"github.com/tristenkelly/chirpy/internal/filter"
+)
+
+const maxContentWarningLen = 100
+
+type preferencesResponse struct {
+	ExpandSensitive bool `json:"expand_sensitive"`
+}
+
+// checkContentWarning runs a content warning through the word filter like
+// a chirp body. It returns a message for the author when the warning can't
+// be used.
+func (cfg *apiConfig) checkContentWarning(cw string) (filter.Result, string) {
+	cw = strings.TrimSpace(cw)
+	filtered := cfg.filter.Apply(cw)
+	if filtered.Rejected {
+		return filtered, "Content warning rejected: " + strings.Join(filtered.Reasons, ", ")
+	}
+	if utf8.RuneCountInString(cw) > maxContentWarningLen {
+		return filtered, "Content warning is too long"
+	}
+	return filtered, ""
+}
+
+// markChirpSensitive lets a moderator force the sensitive flag on, and
+// optionally a content warning. Without a content_warning in the body the
+// author's own warning is kept.
+func (cfg *apiConfig) markChirpSensitive(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+
+	moderatorID, chirp, params, ok := cfg.moderateChirp(w, r)
+	if !ok {
+		return
+	}
+
+	cw := chirp.ContentWarning
+	if strings.TrimSpace(params.ContentWarning) != "" {
+		filtered, msg := cfg.checkContentWarning(params.ContentWarning)
+		if msg != "" {
+			val, _ := json.Marshal(errorResponse{Error: msg})
+			w.WriteHeader(400)
+			w.Write(val)
+			return
+		}
+		cw = filtered.Text
+	}
+
+	err := cfg.db.SetChirpSensitive(r.Context(), database.SetChirpSensitiveParams{
+		ID:             chirp.ID,
+		ContentWarning: cw,
+		UpdatedAt:      time.Now(),
+	})
+	if err != nil {
+		log.Printf("error marking chirp sensitive: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	chirpID := uuid.NullUUID{UUID: chirp.ID, Valid: true}
+	err = cfg.recordModerationAction(r.Context(), moderatorID, "mark_sensitive", params, chirpID, uuid.NullUUID{UUID: chirp.UserID, Valid: true})
+	if err != nil {
+		log.Printf("error recording moderation action: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) getPreferences(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting token: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		log.Printf("token not valid: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		log.Printf("error getting user: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	val, err := json.Marshal(preferencesResponse{ExpandSensitive: user.ExpandSensitive})
+	if err != nil {
+		log.Printf("error marshalling preferences: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(val)
+}
+
+// updatePreferences sets whether sensitive chirps and chirps behind a
+// content warning come back expanded for the caller.
+func (cfg *apiConfig) updatePreferences(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting token: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		log.Printf("token not valid: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	params := preferencesResponse{}
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&params)
+	if err != nil {
+		log.Printf("error decoding preferences: %v", err)
+		w.WriteHeader(400)
+		return
+	}
+
+	err = cfg.db.SetExpandSensitive(r.Context(), database.SetExpandSensitiveParams{
+		ID:              userID,
+		ExpandSensitive: params.ExpandSensitive,
+		UpdatedAt:       time.Now(),
+	})
+	if err != nil {
+		log.Printf("error updating preferences: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	val, err := json.Marshal(params)
+	if err != nil {
+		log.Printf("error marshalling preferences: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(val)
+}
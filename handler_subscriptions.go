@@ -0,0 +1,361 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tristenkelly/chirpy/internal/auth"
+	"github.com/tristenkelly/chirpy/internal/database"
+	"github.com/tristenkelly/chirpy/internal/webhook"
+)
+
+const (
+	subscriptionActive   = "active"
+	subscriptionPastDue  = "past_due"
+	subscriptionCanceled = "canceled"
+	subscriptionExpired  = "expired"
+	subscriptionRefunded = "refunded"
+
+	defaultSubscriptionPlan   = "red"
+	subscriptionPeriod        = 30 * 24 * time.Hour
+	subscriptionGracePeriod   = 7 * 24 * time.Hour
+	subscriptionSweepInterval = 5 * time.Minute
+	maxPolkaBody              = 64 << 10
+
+	notificationSubscriptionExpired = "subscription_expired"
+)
+
+// Polka events that change a subscription. Anything else is acknowledged
+// and ignored.
+const (
+	polkaUpgraded      = "user.upgraded"
+	polkaRenewed       = "subscription.renewed"
+	polkaDowngraded    = "user.downgraded"
+	polkaPaymentFailed = "payment.failed"
+	polkaRefunded      = "payment.refunded"
+)
+
+var polkaEvents = map[string]bool{
+	polkaUpgraded:      true,
+	polkaRenewed:       true,
+	polkaDowngraded:    true,
+	polkaPaymentFailed: true,
+	polkaRefunded:      true,
+}
+
+// polkaData is the data object of a Polka event. The plan and period are
+// optional; without them a period starts now and lasts 30 days.
+type polkaData struct {
+	UserID      string     `json:"user_id"`
+	Plan        string     `json:"plan"`
+	PeriodStart *time.Time `json:"period_start"`
+	PeriodEnd   *time.Time `json:"period_end"`
+}
+
+type subscriptionResponse struct {
+	Plan               string     `json:"plan,omitempty"`
+	Status             string     `json:"status"`
+	IsChirpyRed        bool       `json:"is_chirpy_red"`
+	CurrentPeriodStart *time.Time `json:"current_period_start,omitempty"`
+	CurrentPeriodEnd   *time.Time `json:"current_period_end,omitempty"`
+	GraceUntil         *time.Time `json:"grace_until,omitempty"`
+	CanceledAt         *time.Time `json:"canceled_at,omitempty"`
+}
+
+// subscriptionInstants converts sub's times from the local wall-clock time
+// they are stored as, so they can be compared with time.Now() and shown to
+// clients. Written back, they are stored unchanged.
+func subscriptionInstants(sub database.Subscription) database.Subscription {
+	sub.CreatedAt = fromTimestamp(sub.CreatedAt)
+	sub.UpdatedAt = fromTimestamp(sub.UpdatedAt)
+	sub.CurrentPeriodStart = fromTimestamp(sub.CurrentPeriodStart)
+	sub.CurrentPeriodEnd = fromTimestamp(sub.CurrentPeriodEnd)
+	if sub.GraceUntil.Valid {
+		sub.GraceUntil.Time = fromTimestamp(sub.GraceUntil.Time)
+	}
+	if sub.CanceledAt.Valid {
+		sub.CanceledAt.Time = fromTimestamp(sub.CanceledAt.Time)
+	}
+	return sub
+}
+
+// applyPolkaEvent returns sub as changed by event.
+func applyPolkaEvent(sub database.Subscription, event string, data polkaData, now time.Time) database.Subscription {
+	sub.UpdatedAt = now
+	switch event {
+	case polkaUpgraded, polkaRenewed:
+		// Polka sends UTC; the columns hold local wall-clock time like
+		// every other timestamp.
+		start := now
+		if data.PeriodStart != nil {
+			start = data.PeriodStart.Local()
+		}
+		end := start.Add(subscriptionPeriod)
+		if data.PeriodEnd != nil {
+			end = data.PeriodEnd.Local()
+		}
+		if data.Plan != "" {
+			sub.Plan = data.Plan
+		}
+		sub.Status = subscriptionActive
+		sub.CurrentPeriodStart = start
+		sub.CurrentPeriodEnd = end
+		sub.GraceUntil = sql.NullTime{}
+		sub.CanceledAt = sql.NullTime{}
+	case polkaPaymentFailed:
+		// Polka retries the charge for a while, so the user keeps Red
+		// for a grace period past the end of what they paid for.
+		if sub.Status != subscriptionActive {
+			break
+		}
+		grace := sub.CurrentPeriodEnd
+		if grace.Before(now) {
+			grace = now
+		}
+		sub.Status = subscriptionPastDue
+		sub.GraceUntil = sql.NullTime{Time: grace.Add(subscriptionGracePeriod), Valid: true}
+	case polkaDowngraded:
+		// A cancellation takes effect when the paid period ends.
+		if sub.Status != subscriptionActive && sub.Status != subscriptionPastDue {
+			break
+		}
+		sub.Status = subscriptionCanceled
+		sub.CanceledAt = sql.NullTime{Time: now, Valid: true}
+	case polkaRefunded:
+		// A refund ends the subscription straight away.
+		sub.Status = subscriptionRefunded
+		sub.CurrentPeriodEnd = now
+		sub.GraceUntil = sql.NullTime{}
+		if !sub.CanceledAt.Valid {
+			sub.CanceledAt = sql.NullTime{Time: now, Valid: true}
+		}
+	}
+	return sub
+}
+
+// polkaWebhook handles Polka's billing webhooks. Polka signs each request
+// with an HMAC of its timestamp and raw body; retries reuse the event ID,
+// which is recorded in the same transaction as the change so a retried
+// event is only applied once.
+func (cfg *apiConfig) polkaWebhook(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxPolkaBody))
+	if err != nil {
+		log.Printf("error reading polka webhook: %v", err)
+		w.WriteHeader(400)
+		return
+	}
+
+	err = webhook.VerifyHeaders(r.Header.Get("Polka-Timestamp"), r.Header.Get("Polka-Signature"), cfg.polka, body, time.Now(), webhook.DefaultTolerance)
+	if err != nil {
+		log.Printf("rejected polka webhook: %v", err)
+		cfg.metrics.Webhooks.WithLabelValues("polka", "rejected").Inc()
+		w.WriteHeader(401)
+		return
+	}
+
+	type paramaters struct {
+		ID    string    `json:"id"`
+		Event string    `json:"event"`
+		Data  polkaData `json:"data"`
+	}
+	params := paramaters{}
+	err = json.Unmarshal(body, &params)
+	if err != nil {
+		log.Printf("error decoding json: %v", err)
+		w.WriteHeader(400)
+		return
+	}
+	if params.ID == "" {
+		log.Printf("polka webhook without an event id")
+		w.WriteHeader(400)
+		return
+	}
+
+	if !polkaEvents[params.Event] {
+		w.WriteHeader(204)
+		return
+	}
+	userID, err := uuid.Parse(params.Data.UserID)
+	if err != nil {
+		log.Printf("error parsing string into uuid: %v", err)
+		w.WriteHeader(400)
+		return
+	}
+
+	tx, err := cfg.sqlDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("error starting transaction: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	defer tx.Rollback()
+	q := cfg.withTx(tx)
+
+	now := time.Now()
+	inserted, err := q.RecordWebhookEvent(r.Context(), database.RecordWebhookEventParams{
+		ID:         params.ID,
+		Event:      params.Event,
+		ReceivedAt: now,
+	})
+	if err != nil {
+		log.Printf("error recording polka event: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	if inserted == 0 {
+		// Already handled; Polka just didn't hear back the first time.
+		cfg.metrics.Webhooks.WithLabelValues("polka", "duplicate").Inc()
+		w.WriteHeader(204)
+		return
+	}
+
+	if _, err := q.GetUserByID(r.Context(), userID); err != nil {
+		log.Printf("error getting user in table: %v", err)
+		w.WriteHeader(404)
+		return
+	}
+
+	sub, err := q.GetSubscription(r.Context(), userID)
+	if err == nil {
+		sub = subscriptionInstants(sub)
+	} else if errors.Is(err, sql.ErrNoRows) {
+		if params.Event != polkaUpgraded && params.Event != polkaRenewed {
+			// Nothing to cancel or mark unpaid.
+			if err := tx.Commit(); err != nil {
+				log.Printf("error committing polka event: %v", err)
+				w.WriteHeader(500)
+				return
+			}
+			w.WriteHeader(204)
+			return
+		}
+		sub = database.Subscription{UserID: userID, CreatedAt: now, Plan: defaultSubscriptionPlan}
+	} else if err != nil {
+		log.Printf("error getting subscription: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	sub = applyPolkaEvent(sub, params.Event, params.Data, now)
+	_, err = q.UpsertSubscription(r.Context(), database.UpsertSubscriptionParams{
+		UserID:             sub.UserID,
+		CreatedAt:          sub.CreatedAt,
+		UpdatedAt:          sub.UpdatedAt,
+		Plan:               sub.Plan,
+		Status:             sub.Status,
+		CurrentPeriodStart: sub.CurrentPeriodStart,
+		CurrentPeriodEnd:   sub.CurrentPeriodEnd,
+		GraceUntil:         sub.GraceUntil,
+		CanceledAt:         sub.CanceledAt,
+	})
+	if err != nil {
+		log.Printf("error saving subscription: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	// Past-due and canceled subscriptions keep Red until their grace
+	// period or paid period runs out. The check is left to the database,
+	// like the sweeper's, since it compares stored timestamps.
+	err = q.SyncChirpyRed(r.Context(), database.SyncChirpyRedParams{
+		ID:        userID,
+		UpdatedAt: now,
+	})
+	if err != nil {
+		log.Printf("error updating user: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("error committing polka event: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	cfg.metrics.Webhooks.WithLabelValues("polka", "applied").Inc()
+	w.WriteHeader(204)
+}
+
+// getSubscription shows the caller's billing status. Users who have never
+// subscribed get a status of "none".
+func (cfg *apiConfig) getSubscription(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting token: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		log.Printf("token not valid: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		log.Printf("error getting user: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	resp := subscriptionResponse{Status: "none", IsChirpyRed: user.IsChirpyRed}
+	sub, err := cfg.db.GetSubscription(r.Context(), userID)
+	if err == nil {
+		sub = subscriptionInstants(sub)
+		resp.Plan = sub.Plan
+		resp.Status = sub.Status
+		resp.CurrentPeriodStart = &sub.CurrentPeriodStart
+		resp.CurrentPeriodEnd = &sub.CurrentPeriodEnd
+		if sub.GraceUntil.Valid {
+			resp.GraceUntil = &sub.GraceUntil.Time
+		}
+		if sub.CanceledAt.Valid {
+			resp.CanceledAt = &sub.CanceledAt.Time
+		}
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("error getting subscription: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	val, err := json.Marshal(resp)
+	if err != nil {
+		log.Printf("error marshalling subscription: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(val)
+}
+
+// runSubscriptionSweeper expires subscriptions whose paid or grace period
+// has run out and takes away Chirpy Red. Each row is expired by a single
+// UPDATE, so users are notified once even with several instances running.
+func (cfg *apiConfig) runSubscriptionSweeper(ctx context.Context) {
+	ticker := time.NewTicker(subscriptionSweepInterval)
+	defer ticker.Stop()
+	for {
+		expired, err := cfg.db.ExpireSubscriptions(ctx, time.Now())
+		if err != nil {
+			log.Printf("error expiring subscriptions: %v", err)
+		}
+		for _, userID := range expired {
+			cfg.notify(ctx, userID, notificationSubscriptionExpired, uuid.NullUUID{})
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/tristenkelly/chirpy/internal/database"
+)
+
+const (
+	// trendBaseline is how far back we look to learn a tag's normal usage.
+	trendBaseline = 7 * 24 * time.Hour
+	// trendBaselinePrior is added to every tag's baseline rate, in authors
+	// per hour, so a brand new tag needs real momentum to outrank an
+	// established one.
+	trendBaselinePrior = 1.0 / 24
+	minTrendAuthors    = 3
+	minTrendScore      = 2.0
+	maxTrends          = 10
+	trendsInterval     = 5 * time.Minute
+)
+
+// trendWindows are the recent windows compared against the baseline. A
+// short window catches sudden spikes, a longer one steadier climbs.
+var trendWindows = []time.Duration{time.Hour, 6 * time.Hour}
+
+type trendResponse struct {
+	Tag     string  `json:"tag"`
+	Authors int64   `json:"authors"`
+	Score   float64 `json:"score"`
+}
+
+// trendCache holds the latest computed trends. It is refreshed by
+// runTrends and read by every GET /api/trends.
+type trendCache struct {
+	mu        sync.RWMutex
+	trends    []trendResponse
+	updatedAt time.Time
+}
+
+func (c *trendCache) get() ([]trendResponse, time.Time) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.trends, c.updatedAt
+}
+
+func (c *trendCache) set(trends []trendResponse, updatedAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.trends = trends
+	c.updatedAt = updatedAt
+}
+
+// computeTrends scores hashtags by how much faster distinct authors are
+// using them in each recent window than over the baseline before it.
+// Counting authors rather than chirps keeps one account, or a handful,
+// from pushing a tag up by repeating it. Hidden chirps, suspended authors
+// and anything but public chirps are left out by the query.
+func (cfg *apiConfig) computeTrends(ctx context.Context, now time.Time) ([]trendResponse, error) {
+	best := map[string]trendResponse{}
+	for _, window := range trendWindows {
+		rows, err := cfg.db.GetHashtagActivity(ctx, database.GetHashtagActivityParams{
+			WindowStart:   now.Add(-window),
+			BaselineStart: now.Add(-trendBaseline),
+			Now:           now,
+			MinAuthors:    minTrendAuthors,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			recentRate := float64(row.RecentAuthors) / window.Hours()
+			baselineRate := float64(row.BaselineAuthors) / (trendBaseline - window).Hours()
+			score := recentRate / (baselineRate + trendBaselinePrior)
+			if score < minTrendScore || score <= best[row.Tag].Score {
+				continue
+			}
+			best[row.Tag] = trendResponse{
+				Tag:     "#" + row.Tag,
+				Authors: row.RecentAuthors,
+				Score:   score,
+			}
+		}
+	}
+
+	var trends []trendResponse
+	for _, trend := range best {
+		// A tag the word filter would touch doesn't get promoted.
+		result := cfg.filter.Apply(trend.Tag)
+		if result.Rejected || result.Flagged || result.Text != trend.Tag {
+			continue
+		}
+		trends = append(trends, trend)
+	}
+	slices.SortFunc(trends, func(a, b trendResponse) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.Authors, a.Authors)
+	})
+	if len(trends) > maxTrends {
+		trends = trends[:maxTrends]
+	}
+	return trends, nil
+}
+
+// runTrends recomputes the trends cache every trendsInterval. Failures
+// keep the previous trends in place.
+func (cfg *apiConfig) runTrends(ctx context.Context) {
+	ticker := time.NewTicker(trendsInterval)
+	defer ticker.Stop()
+	for {
+		now := time.Now()
+		trends, err := cfg.computeTrends(ctx, now)
+		if err != nil {
+			log.Printf("error computing trends: %v", err)
+		} else {
+			cfg.trends.set(trends, now)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// getTrends serves the cached trends, leaving out tags the viewer muted.
+func (cfg *apiConfig) getTrends(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	viewer, loggedIn := cfg.viewerID(r)
+	filter, err := cfg.loadChirpFilter(r.Context(), viewer, loggedIn)
+	if err != nil {
+		log.Printf("error loading chirp filter: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	type trendsPage struct {
+		Trends    []trendResponse `json:"trends"`
+		UpdatedAt *time.Time      `json:"updated_at,omitempty"`
+	}
+
+	trends, updatedAt := cfg.trends.get()
+	resp := trendsPage{Trends: []trendResponse{}}
+	if !updatedAt.IsZero() {
+		resp.UpdatedAt = &updatedAt
+	}
+	for _, trend := range trends {
+		if slices.Contains(filter.mutedWords, trend.Tag) || slices.Contains(filter.mutedWords, trend.Tag[1:]) {
+			continue
+		}
+		resp.Trends = append(resp.Trends, trend)
+	}
+
+	val, err := json.Marshal(resp)
+	if err != nil {
+		log.Printf("error marshalling trends: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(val)
+}
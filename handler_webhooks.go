@@ -0,0 +1,460 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tristenkelly/chirpy/internal/auth"
+	"github.com/tristenkelly/chirpy/internal/database"
+	"github.com/tristenkelly/chirpy/internal/webhook"
+)
+
+const maxWebhookEndpoints = 10
+
+type webhookEndpointResponse struct {
+	ID           uuid.UUID  `json:"id"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	URL          string     `json:"url"`
+	Events       []string   `json:"events"`
+	Enabled      bool       `json:"enabled"`
+	FailureCount int32      `json:"failure_count"`
+	DisabledAt   *time.Time `json:"disabled_at,omitempty"`
+	// Secret is only returned when the endpoint is created.
+	Secret string `json:"secret,omitempty"`
+}
+
+type webhookDeliveryResponse struct {
+	ID            uuid.UUID       `json:"id"`
+	CreatedAt     time.Time       `json:"created_at"`
+	EventID       uuid.UUID       `json:"event_id"`
+	Event         string          `json:"event"`
+	Status        string          `json:"status"`
+	Attempts      int32           `json:"attempts"`
+	ResponseCode  int32           `json:"response_code,omitempty"`
+	LastError     string          `json:"last_error,omitempty"`
+	NextAttemptAt *time.Time      `json:"next_attempt_at,omitempty"`
+	CompletedAt   *time.Time      `json:"completed_at,omitempty"`
+	Payload       json.RawMessage `json:"payload"`
+}
+
+func webhookEndpointToResponse(e database.WebhookEndpoint) webhookEndpointResponse {
+	resp := webhookEndpointResponse{
+		ID:           e.ID,
+		CreatedAt:    e.CreatedAt,
+		UpdatedAt:    e.UpdatedAt,
+		URL:          e.Url,
+		Events:       e.Events,
+		Enabled:      !e.DisabledAt.Valid,
+		FailureCount: e.FailureCount,
+	}
+	if e.DisabledAt.Valid {
+		resp.DisabledAt = &e.DisabledAt.Time
+	}
+	return resp
+}
+
+func webhookDeliveryToResponse(d database.WebhookDelivery) webhookDeliveryResponse {
+	resp := webhookDeliveryResponse{
+		ID:           d.ID,
+		CreatedAt:    d.CreatedAt,
+		EventID:      d.EventID,
+		Event:        d.Event,
+		Status:       d.Status,
+		Attempts:     d.Attempts,
+		ResponseCode: d.ResponseCode,
+		LastError:    d.LastError,
+		Payload:      json.RawMessage(d.Payload),
+	}
+	if d.Status == webhookPending {
+		resp.NextAttemptAt = &d.NextAttemptAt
+	}
+	if d.CompletedAt.Valid {
+		resp.CompletedAt = &d.CompletedAt.Time
+	}
+	return resp
+}
+
+// validWebhook checks an endpoint URL and event list, returning a message
+// for the client when they're unusable. Events come back sorted and
+// without duplicates.
+func validWebhook(rawURL string, events []string) ([]string, string) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, "URL must be an absolute http or https URL"
+	}
+	if len(events) == 0 {
+		return nil, "At least one event is required"
+	}
+	seen := map[string]bool{}
+	var clean []string
+	for _, event := range events {
+		if !webhookEvents[event] {
+			return nil, "Unknown event " + event
+		}
+		if !seen[event] {
+			seen[event] = true
+			clean = append(clean, event)
+		}
+	}
+	sort.Strings(clean)
+	return clean, ""
+}
+
+// createWebhook registers an endpoint for the caller's events. The signing
+// secret is only ever shown in this response.
+func (cfg *apiConfig) createWebhook(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting token: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		log.Printf("token not valid: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	type paramaters struct {
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+	}
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+
+	params := paramaters{}
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&params)
+	if err != nil {
+		log.Printf("error decoding parameters: %v", err)
+		w.WriteHeader(400)
+		return
+	}
+
+	events, msg := validWebhook(params.URL, params.Events)
+	if msg == "" {
+		count, err := cfg.db.CountWebhookEndpoints(r.Context(), userID)
+		if err != nil {
+			log.Printf("error counting webhooks: %v", err)
+			w.WriteHeader(500)
+			return
+		}
+		if count >= maxWebhookEndpoints {
+			msg = "Too many webhook endpoints"
+		}
+	}
+	if msg != "" {
+		val, _ := json.Marshal(errorResponse{Error: msg})
+		w.WriteHeader(400)
+		w.Write(val)
+		return
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		log.Printf("error generating webhook secret: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	now := time.Now()
+	endpoint, err := cfg.db.CreateWebhookEndpoint(r.Context(), database.CreateWebhookEndpointParams{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		UserID:    userID,
+		Url:       params.URL,
+		Secret:    secret,
+		Events:    events,
+	})
+	if err != nil {
+		log.Printf("error creating webhook: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	resp := webhookEndpointToResponse(endpoint)
+	resp.Secret = endpoint.Secret
+	val, err := json.Marshal(resp)
+	if err != nil {
+		log.Printf("error marshalling webhook: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(201)
+	w.Write(val)
+}
+
+func (cfg *apiConfig) getWebhooks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting token: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		log.Printf("token not valid: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	endpoints, err := cfg.db.GetWebhookEndpoints(r.Context(), userID)
+	if err != nil {
+		log.Printf("error getting webhooks: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	resp := []webhookEndpointResponse{}
+	for _, e := range endpoints {
+		resp = append(resp, webhookEndpointToResponse(e))
+	}
+	val, err := json.Marshal(resp)
+	if err != nil {
+		log.Printf("error marshalling webhooks: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(val)
+}
+
+// ownWebhook loads the {webhookID} endpoint for its owner, writing the
+// error status itself.
+func (cfg *apiConfig) ownWebhook(w http.ResponseWriter, r *http.Request) (database.WebhookEndpoint, bool) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting token: %v", err)
+		w.WriteHeader(401)
+		return database.WebhookEndpoint{}, false
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		log.Printf("token not valid: %v", err)
+		w.WriteHeader(401)
+		return database.WebhookEndpoint{}, false
+	}
+
+	webhookID, err := uuid.Parse(r.PathValue("webhookID"))
+	if err != nil {
+		w.WriteHeader(404)
+		return database.WebhookEndpoint{}, false
+	}
+	endpoint, err := cfg.db.GetWebhookEndpoint(r.Context(), database.GetWebhookEndpointParams{
+		ID:     webhookID,
+		UserID: userID,
+	})
+	if err != nil {
+		w.WriteHeader(404)
+		return database.WebhookEndpoint{}, false
+	}
+	return endpoint, true
+}
+
+// updateWebhook changes an endpoint's URL or events, or turns it on or
+// off. Turning it back on clears its failure count; deliveries still
+// pending resume.
+func (cfg *apiConfig) updateWebhook(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	endpoint, ok := cfg.ownWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	type paramaters struct {
+		URL     *string  `json:"url"`
+		Events  []string `json:"events"`
+		Enabled *bool    `json:"enabled"`
+	}
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+
+	params := paramaters{}
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&params)
+	if err != nil {
+		log.Printf("error decoding parameters: %v", err)
+		w.WriteHeader(400)
+		return
+	}
+
+	now := time.Now()
+	update := database.UpdateWebhookEndpointParams{
+		ID:           endpoint.ID,
+		UserID:       endpoint.UserID,
+		Url:          endpoint.Url,
+		Events:       endpoint.Events,
+		DisabledAt:   endpoint.DisabledAt,
+		FailureCount: endpoint.FailureCount,
+		UpdatedAt:    now,
+	}
+	if params.URL != nil {
+		update.Url = *params.URL
+	}
+	if params.Events != nil {
+		update.Events = params.Events
+	}
+	events, msg := validWebhook(update.Url, update.Events)
+	if msg != "" {
+		val, _ := json.Marshal(errorResponse{Error: msg})
+		w.WriteHeader(400)
+		w.Write(val)
+		return
+	}
+	update.Events = events
+	if params.Enabled != nil {
+		if *params.Enabled && endpoint.DisabledAt.Valid {
+			update.DisabledAt = sql.NullTime{}
+			update.FailureCount = 0
+		} else if !*params.Enabled && !endpoint.DisabledAt.Valid {
+			update.DisabledAt = sql.NullTime{Time: now, Valid: true}
+		}
+	}
+
+	updated, err := cfg.db.UpdateWebhookEndpoint(r.Context(), update)
+	if err != nil {
+		log.Printf("error updating webhook: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	val, err := json.Marshal(webhookEndpointToResponse(updated))
+	if err != nil {
+		log.Printf("error marshalling webhook: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(val)
+}
+
+func (cfg *apiConfig) deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	endpoint, ok := cfg.ownWebhook(w, r)
+	if !ok {
+		return
+	}
+	_, err := cfg.db.DeleteWebhookEndpoint(r.Context(), database.DeleteWebhookEndpointParams{
+		ID:     endpoint.ID,
+		UserID: endpoint.UserID,
+	})
+	if err != nil {
+		log.Printf("error deleting webhook: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+// getWebhookDeliveries pages through an endpoint's delivery log, newest
+// first. Finished deliveries are kept for 30 days.
+func (cfg *apiConfig) getWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	endpoint, ok := cfg.ownWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	before, beforeID, limit, err := pageParams(r)
+	if err != nil {
+		log.Printf("invalid pagination params: %v", err)
+		w.WriteHeader(400)
+		return
+	}
+	data, err := cfg.db.GetWebhookDeliveriesPage(r.Context(), database.GetWebhookDeliveriesPageParams{
+		EndpointID:      endpoint.ID,
+		BeforeCreatedAt: before,
+		BeforeID:        beforeID,
+		PageSize:        limit,
+	})
+	if err != nil {
+		log.Printf("error getting webhook deliveries: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	type deliveryPage struct {
+		Deliveries []webhookDeliveryResponse `json:"deliveries"`
+		NextCursor string                    `json:"next_cursor,omitempty"`
+	}
+
+	resp := deliveryPage{Deliveries: []webhookDeliveryResponse{}}
+	for _, d := range data {
+		resp.Deliveries = append(resp.Deliveries, webhookDeliveryToResponse(d))
+	}
+	if len(data) == int(limit) {
+		last := data[len(data)-1]
+		resp.NextCursor = encodeCursor(last.CreatedAt, last.ID)
+	}
+
+	val, err := json.Marshal(resp)
+	if err != nil {
+		log.Printf("error marshalling webhook deliveries: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(val)
+}
+
+// redeliverWebhook queues a logged delivery again as a new delivery with
+// the same event ID and payload.
+func (cfg *apiConfig) redeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	endpoint, ok := cfg.ownWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	deliveryID, err := uuid.Parse(r.PathValue("deliveryID"))
+	if err != nil {
+		w.WriteHeader(404)
+		return
+	}
+	original, err := cfg.db.GetWebhookDelivery(r.Context(), database.GetWebhookDeliveryParams{
+		ID:         deliveryID,
+		EndpointID: endpoint.ID,
+	})
+	if err != nil {
+		w.WriteHeader(404)
+		return
+	}
+
+	now := time.Now()
+	delivery, err := cfg.db.CreateWebhookDelivery(r.Context(), database.CreateWebhookDeliveryParams{
+		ID:            uuid.New(),
+		CreatedAt:     now,
+		EndpointID:    endpoint.ID,
+		EventID:       original.EventID,
+		Event:         original.Event,
+		Payload:       original.Payload,
+		NextAttemptAt: now,
+	})
+	if err != nil {
+		log.Printf("error queueing redelivery: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	val, err := json.Marshal(webhookDeliveryToResponse(delivery))
+	if err != nil {
+		log.Printf("error marshalling webhook delivery: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(202)
+	w.Write(val)
+}
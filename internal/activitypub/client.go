@@ -0,0 +1,164 @@
+package activitypub
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// maxResponseBytes caps what we read from a remote server.
+const maxResponseBytes = 1 << 20
+
+// Client talks to remote servers. HTTP should refuse non-public
+// addresses; see safehttp.
+type Client struct {
+	HTTP      *http.Client
+	UserAgent string
+}
+
+// FetchActor retrieves and sanity-checks a remote actor document.
+func (c *Client) FetchActor(ctx context.Context, actorURL string) (Actor, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, actorURL, nil)
+	if err != nil {
+		return Actor{}, err
+	}
+	req.Header.Set("Accept", ContentType+", "+LDContentType)
+	req.Header.Set("User-Agent", c.UserAgent)
+
+	actor := Actor{}
+	if err := c.getJSON(req, &actor); err != nil {
+		return Actor{}, err
+	}
+	// The document must describe the actor we asked for, and the key must
+	// belong to it, or a server could hand out someone else's identity.
+	if actor.ID != actorURL {
+		return Actor{}, fmt.Errorf("actor %s has id %s", actorURL, actor.ID)
+	}
+	if actor.Inbox == "" || actor.PublicKey.PublicKeyPem == "" || actor.PublicKey.Owner != actor.ID {
+		return Actor{}, fmt.Errorf("actor %s is missing an inbox or key", actorURL)
+	}
+	if !sameHost(actor.ID, actor.Inbox) || !sameHost(actor.ID, actor.PublicKey.ID) {
+		return Actor{}, fmt.Errorf("actor %s points at another host", actorURL)
+	}
+	return actor, nil
+}
+
+// Webfinger resolves user@host to an actor URL. scheme is normally https;
+// development instances on plain http pass "http".
+func (c *Client) Webfinger(ctx context.Context, account, scheme string) (string, error) {
+	account = strings.TrimPrefix(strings.TrimPrefix(account, "acct:"), "@")
+	_, host, ok := strings.Cut(account, "@")
+	if !ok || host == "" {
+		return "", fmt.Errorf("invalid account %q", account)
+	}
+	u := url.URL{
+		Scheme:   scheme,
+		Host:     host,
+		Path:     "/.well-known/webfinger",
+		RawQuery: url.Values{"resource": {"acct:" + account}}.Encode(),
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/jrd+json")
+	req.Header.Set("User-Agent", c.UserAgent)
+
+	jrd := WebFinger{}
+	if err := c.getJSON(req, &jrd); err != nil {
+		return "", err
+	}
+	for _, link := range jrd.Links {
+		if link.Rel == "self" && isActivityType(link.Type) {
+			return link.Href, nil
+		}
+	}
+	return "", fmt.Errorf("no actor link for %s", account)
+}
+
+// Deliver POSTs a signed activity to an inbox.
+func (c *Client) Deliver(ctx context.Context, inbox string, body []byte, keyID string, key *rsa.PrivateKey) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, inbox, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", ContentType)
+	req.Header.Set("User-Agent", c.UserAgent)
+	if err := Sign(req, body, keyID, key); err != nil {
+		return err
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBytes))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("inbox %s returned %d", inbox, resp.StatusCode)
+	}
+	return nil
+}
+
+func (c *Client) getJSON(req *http.Request, v any) error {
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %d", req.URL, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(v)
+}
+
+// IsActivityRequest reports whether a Content-Type or Accept header asks
+// for ActivityPub JSON.
+func IsActivityRequest(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		if isActivityType(strings.TrimSpace(part)) {
+			return true
+		}
+	}
+	return false
+}
+
+func isActivityType(s string) bool {
+	mediaType, params, err := mime.ParseMediaType(s)
+	if err != nil {
+		return false
+	}
+	switch mediaType {
+	case ContentType:
+		return true
+	case "application/ld+json":
+		return params["profile"] == Context
+	}
+	return false
+}
+
+// KeyOwner returns the actor a key ID belongs to, which by convention is
+// the key ID without its fragment.
+func KeyOwner(keyID string) string {
+	owner, _, _ := strings.Cut(keyID, "#")
+	return owner
+}
+
+func sameHost(a, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil || ua.Host == "" {
+		return false
+	}
+	ub, err := url.Parse(b)
+	if err != nil || ub.Host == "" {
+		return false
+	}
+	return strings.EqualFold(ua.Host, ub.Host)
+}
@@ -0,0 +1,302 @@
+package activitypub
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tristenkelly/chirpy/internal/safehttp"
+)
+
+// instance is a stand-in for a Chirpy server with one user, alice, on a
+// local httptest server. Its inbox checks signatures with VerifyRequest,
+// as Chirpy's does, looking keys up through a cache of remote actors.
+type instance struct {
+	t        *testing.T
+	srv      *httptest.Server
+	host     string
+	key      *rsa.PrivateKey
+	actor    Actor
+	client   *Client
+	received chan Activity
+
+	mu      sync.Mutex
+	keys    map[string]PublicKey
+	fetches int
+}
+
+func newInstance(t *testing.T) *instance {
+	privatePEM, publicPEM, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ParsePrivateKey(privatePEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := &instance{
+		t:        t,
+		key:      key,
+		received: make(chan Activity, 1),
+		keys:     map[string]PublicKey{},
+		client: &Client{
+			// Both instances live on loopback, which safehttp refuses
+			// without an allowlist entry.
+			HTTP: safehttp.NewClient(safehttp.Options{
+				Timeout:      5 * time.Second,
+				MaxRedirects: 3,
+				Allow:        []netip.Prefix{netip.MustParsePrefix("127.0.0.1/32")},
+			}),
+			UserAgent: "Chirpy-Test/1.0",
+		},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/webfinger", in.webfinger)
+	mux.HandleFunc("GET /ap/users/alice", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		json.NewEncoder(w).Encode(in.actor)
+	})
+	mux.HandleFunc("POST /ap/users/alice/inbox", in.inbox)
+	in.srv = httptest.NewServer(mux)
+	t.Cleanup(in.srv.Close)
+
+	in.host = in.srv.Listener.Addr().String()
+	actorID := in.srv.URL + "/ap/users/alice"
+	in.actor = Actor{
+		Context:           []string{Context, SecurityV1},
+		ID:                actorID,
+		Type:              "Person",
+		PreferredUsername: "alice",
+		Inbox:             actorID + "/inbox",
+		PublicKey: PublicKey{
+			ID:           actorID + "#main-key",
+			Owner:        actorID,
+			PublicKeyPem: publicPEM,
+		},
+	}
+	return in
+}
+
+func (in *instance) webfinger(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("resource") != "acct:alice@"+in.host {
+		w.WriteHeader(404)
+		return
+	}
+	w.Header().Set("Content-Type", "application/jrd+json")
+	json.NewEncoder(w).Encode(WebFinger{
+		Subject: "acct:alice@" + in.host,
+		Links:   []WebFingerLink{{Rel: "self", Type: ContentType, Href: in.actor.ID}},
+	})
+}
+
+// lookupKey serves keys from the cache unless asked to refresh, like
+// Chirpy's remote actor table.
+func (in *instance) lookupKey(ctx context.Context, actorID string, refresh bool) (PublicKey, error) {
+	in.mu.Lock()
+	key, ok := in.keys[actorID]
+	in.mu.Unlock()
+	if ok && !refresh {
+		return key, nil
+	}
+	actor, err := in.client.FetchActor(ctx, actorID)
+	if err != nil {
+		return PublicKey{}, err
+	}
+	key = PublicKey{ID: actor.PublicKey.ID, Owner: actor.ID, PublicKeyPem: actor.PublicKey.PublicKeyPem}
+	in.mu.Lock()
+	in.keys[actorID] = key
+	in.fetches++
+	in.mu.Unlock()
+	return key, nil
+}
+
+func (in *instance) inbox(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(400)
+		return
+	}
+	activity := Activity{}
+	if err := json.Unmarshal(body, &activity); err != nil {
+		w.WriteHeader(400)
+		return
+	}
+	signer, err := VerifyRequest(r, body, in.lookupKey)
+	if err != nil {
+		in.t.Logf("inbox: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+	if signer != activity.Actor {
+		w.WriteHeader(401)
+		return
+	}
+	in.received <- activity
+	w.WriteHeader(202)
+}
+
+// follow has from look up alice on to by WebFinger and deliver a signed
+// Follow to her inbox.
+func follow(t *testing.T, from, to *instance, key *rsa.PrivateKey) error {
+	t.Helper()
+	ctx := context.Background()
+	actorURL, err := from.client.Webfinger(ctx, "@alice@"+to.host, "http")
+	if err != nil {
+		t.Fatalf("Webfinger: %v", err)
+	}
+	remote, err := from.client.FetchActor(ctx, actorURL)
+	if err != nil {
+		t.Fatalf("FetchActor: %v", err)
+	}
+
+	activity, err := NewActivity(from.actor.ID+"#follows/1", "Follow", from.actor.ID, remote.ID, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := json.Marshal(activity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return from.client.Deliver(ctx, remote.Inbox, body, from.actor.PublicKey.ID, key)
+}
+
+func TestFederationBetweenInstances(t *testing.T) {
+	a := newInstance(t)
+	b := newInstance(t)
+
+	if err := follow(t, a, b, a.key); err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+	select {
+	case got := <-b.received:
+		if got.Type != "Follow" || got.Actor != a.actor.ID {
+			t.Errorf("b received %s from %s", got.Type, got.Actor)
+		}
+		object, err := ParseObject(got.Object)
+		if err != nil || object.ID != b.actor.ID {
+			t.Errorf("Follow object = %+v, %v; want %s", object, err, b.actor.ID)
+		}
+	default:
+		t.Fatal("b's inbox accepted the Follow but didn't record it")
+	}
+}
+
+func TestFederationRejectsForgedSignature(t *testing.T) {
+	a := newInstance(t)
+	b := newInstance(t)
+	mallory := newInstance(t)
+
+	// Signed with mallory's key while claiming to be a.
+	err := follow(t, a, b, mallory.key)
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Deliver with a forged signature: err = %v, want a 401", err)
+	}
+	select {
+	case got := <-b.received:
+		t.Errorf("b accepted a forged %s", got.Type)
+	default:
+	}
+}
+
+func TestFederationRefetchesRotatedKey(t *testing.T) {
+	a := newInstance(t)
+	b := newInstance(t)
+
+	if err := follow(t, a, b, a.key); err != nil {
+		t.Fatalf("first Deliver: %v", err)
+	}
+	<-b.received
+
+	// a rotates its key; b still has the old one cached.
+	privatePEM, publicPEM, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.key, err = ParsePrivateKey(privatePEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.actor.PublicKey.PublicKeyPem = publicPEM
+
+	if err := follow(t, a, b, a.key); err != nil {
+		t.Fatalf("Deliver after rotation: %v", err)
+	}
+	<-b.received
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.fetches != 2 {
+		t.Errorf("b fetched a's actor %d times, want 2", b.fetches)
+	}
+	if b.keys[a.actor.ID].PublicKeyPem != publicPEM {
+		t.Error("b's cache still has the old key")
+	}
+}
+
+func TestFetchActorChecksIdentity(t *testing.T) {
+	a := newInstance(t)
+	good := a.actor
+
+	tests := []struct {
+		name  string
+		actor func(Actor) Actor
+	}{
+		{"different id", func(ac Actor) Actor {
+			ac.ID = "http://elsewhere.example/ap/users/alice"
+			return ac
+		}},
+		{"key owned by someone else", func(ac Actor) Actor {
+			ac.PublicKey.Owner = "http://elsewhere.example/ap/users/bob"
+			return ac
+		}},
+		{"inbox on another host", func(ac Actor) Actor {
+			ac.Inbox = "http://elsewhere.example/inbox"
+			return ac
+		}},
+		{"key on another host", func(ac Actor) Actor {
+			ac.PublicKey.ID = "http://elsewhere.example/keys/1"
+			return ac
+		}},
+		{"no key", func(ac Actor) Actor {
+			ac.PublicKey.PublicKeyPem = ""
+			return ac
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a.actor = tt.actor(good)
+			if _, err := a.client.FetchActor(context.Background(), good.ID); err == nil {
+				t.Error("expected FetchActor to refuse the document")
+			}
+		})
+	}
+
+	a.actor = good
+	if _, err := a.client.FetchActor(context.Background(), good.ID); err != nil {
+		t.Errorf("FetchActor of a valid actor: %v", err)
+	}
+}
+
+func TestIsActivityRequest(t *testing.T) {
+	tests := map[string]bool{
+		ContentType:                       true,
+		LDContentType:                     true,
+		"text/html, " + ContentType:       true,
+		"application/ld+json":             false,
+		"application/json":                false,
+		"text/html,application/xhtml+xml": false,
+	}
+	for header, want := range tests {
+		if got := IsActivityRequest(header); got != want {
+			t.Errorf("IsActivityRequest(%q) = %v, want %v", header, got, want)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package activitypub
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// MaxClockSkew is how far a signed request's Date may be from our clock.
+const MaxClockSkew = time.Hour
+
+var ErrInvalidSignature = errors.New("invalid HTTP signature")
+
+// GenerateKey creates an RSA key pair for an actor, PEM encoded.
+func GenerateKey() (privatePEM, publicPEM string, err error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return "", "", err
+	}
+	privateDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", "", err
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return "", "", err
+	}
+	privatePEM = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}))
+	publicPEM = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}))
+	return privatePEM, publicPEM, nil
+}
+
+func ParsePrivateKey(s string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(s))
+	if block == nil {
+		return nil, errors.New("no PEM block in private key")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not RSA")
+	}
+	return rsaKey, nil
+}
+
+func ParsePublicKey(s string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(s))
+	if block == nil {
+		return nil, errors.New("no PEM block in public key")
+	}
+	var key any
+	var err error
+	if block.Type == "RSA PUBLIC KEY" {
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	} else {
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("public key is not RSA")
+	}
+	return rsaKey, nil
+}
+
+// Digest returns the Digest header value for a body.
+func Digest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "SHA-256=" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// Sign adds Date, Digest (for requests with a body) and Signature headers
+// to req, following draft-cavage-http-signatures as the fediverse uses it.
+func Sign(req *http.Request, body []byte, keyID string, key *rsa.PrivateKey) error {
+	req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	headers := []string{"(request-target)", "host", "date"}
+	if body != nil {
+		req.Header.Set("Digest", Digest(body))
+		headers = append(headers, "digest")
+	}
+
+	sum := sha256.Sum256([]byte(signingString(req, headers)))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Signature", fmt.Sprintf(`keyId="%s",algorithm="rsa-sha256",headers="%s",signature="%s"`,
+		keyID, strings.Join(headers, " "), base64.StdEncoding.EncodeToString(sig)))
+	return nil
+}
+
+// SignatureKeyID returns the keyId named in a request's Signature header,
+// so the caller can look up the key before calling Verify.
+func SignatureKeyID(req *http.Request) (string, error) {
+	params, err := parseSignature(req.Header.Get("Signature"))
+	if err != nil {
+		return "", err
+	}
+	return params["keyId"], nil
+}
+
+// Verify checks a signed request against the signer's public key. The
+// signature must cover the request target, host and date, and the digest
+// when there is a body; the date must be recent and the digest must match.
+func Verify(req *http.Request, body []byte, key *rsa.PublicKey) error {
+	params, err := parseSignature(req.Header.Get("Signature"))
+	if err != nil {
+		return err
+	}
+	if alg := params["algorithm"]; alg != "" && alg != "rsa-sha256" && alg != "hs2019" {
+		return fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidSignature, alg)
+	}
+	headers := strings.Fields(strings.ToLower(params["headers"]))
+	if len(headers) == 0 {
+		headers = []string{"date"}
+	}
+	required := []string{"(request-target)", "host", "date"}
+	if body != nil {
+		required = append(required, "digest")
+	}
+	for _, h := range required {
+		if !slices.Contains(headers, h) {
+			return fmt.Errorf("%w: %s is not signed", ErrInvalidSignature, h)
+		}
+	}
+
+	date, err := http.ParseTime(req.Header.Get("Date"))
+	if err != nil {
+		return fmt.Errorf("%w: bad date", ErrInvalidSignature)
+	}
+	if d := time.Since(date); d > MaxClockSkew || d < -MaxClockSkew {
+		return fmt.Errorf("%w: date out of range", ErrInvalidSignature)
+	}
+	if body != nil && req.Header.Get("Digest") != Digest(body) {
+		return fmt.Errorf("%w: digest mismatch", ErrInvalidSignature)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(params["signature"])
+	if err != nil {
+		return fmt.Errorf("%w: bad encoding", ErrInvalidSignature)
+	}
+	sum := sha256.Sum256([]byte(signingString(req, headers)))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, sum[:], sig); err != nil {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// KeyLookup returns the key an actor publishes. With refresh set it must
+// fetch the actor again rather than use a cached copy.
+type KeyLookup func(ctx context.Context, actorID string, refresh bool) (PublicKey, error)
+
+// VerifyRequest checks an incoming request's signature against the key
+// named by its keyId and returns the actor that signed it. A failed check
+// with a cached key looks the actor up again once, in case they rotated
+// their key.
+func VerifyRequest(req *http.Request, body []byte, lookup KeyLookup) (string, error) {
+	keyID, err := SignatureKeyID(req)
+	if err != nil {
+		return "", err
+	}
+	actorID := KeyOwner(keyID)
+	err = ErrInvalidSignature
+	for _, refresh := range []bool{false, true} {
+		var key PublicKey
+		key, err = lookup(req.Context(), actorID, refresh)
+		if err != nil {
+			return "", err
+		}
+		if key.ID != keyID {
+			err = fmt.Errorf("%w: unknown key %s", ErrInvalidSignature, keyID)
+			continue
+		}
+		var publicKey *rsa.PublicKey
+		publicKey, err = ParsePublicKey(key.PublicKeyPem)
+		if err != nil {
+			continue
+		}
+		if err = Verify(req, body, publicKey); err == nil {
+			return key.Owner, nil
+		}
+	}
+	return "", err
+}
+
+func signingString(req *http.Request, headers []string) string {
+	lines := make([]string, 0, len(headers))
+	for _, h := range headers {
+		var value string
+		switch h {
+		case "(request-target)":
+			value = strings.ToLower(req.Method) + " " + req.URL.RequestURI()
+		case "host":
+			value = req.Host
+			if value == "" {
+				value = req.URL.Host
+			}
+		default:
+			value = strings.Join(req.Header.Values(h), ", ")
+		}
+		lines = append(lines, h+": "+value)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func parseSignature(header string) (map[string]string, error) {
+	if header == "" {
+		return nil, fmt.Errorf("%w: missing Signature header", ErrInvalidSignature)
+	}
+	params := map[string]string{}
+	for _, part := range strings.Split(header, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		params[k] = strings.Trim(v, `"`)
+	}
+	if params["keyId"] == "" || params["signature"] == "" {
+		return nil, fmt.Errorf("%w: missing keyId or signature", ErrInvalidSignature)
+	}
+	return params, nil
+}
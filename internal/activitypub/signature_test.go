@@ -0,0 +1,279 @@
+package activitypub
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testKeyID = "https://a.example/ap/users/1#main-key"
+
+func testKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	privatePEM, _, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ParsePrivateKey(privatePEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// signAt signs req like Sign but with a chosen Date, so tests can produce
+// requests from a skewed clock.
+func signAt(t *testing.T, req *http.Request, body []byte, key *rsa.PrivateKey, date time.Time, headers []string) {
+	t.Helper()
+	req.Header.Set("Date", date.UTC().Format(http.TimeFormat))
+	if body != nil {
+		req.Header.Set("Digest", Digest(body))
+	}
+	sum := sha256.Sum256([]byte(signingString(req, headers)))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Signature", fmt.Sprintf(`keyId="%s",algorithm="rsa-sha256",headers="%s",signature="%s"`,
+		testKeyID, strings.Join(headers, " "), base64.StdEncoding.EncodeToString(sig)))
+}
+
+func newInboxRequest(body []byte) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "https://b.example/ap/users/2/inbox", bytes.NewReader(body))
+	req.Header.Set("Content-Type", ContentType)
+	return req
+}
+
+func TestSignVerifyRoundTrip(t *testing.T) {
+	key := testKey(t)
+	body := []byte(`{"type":"Follow"}`)
+
+	req := newInboxRequest(body)
+	if err := Sign(req, body, testKeyID, key); err != nil {
+		t.Fatal(err)
+	}
+	if got := req.Header.Get("Digest"); got != Digest(body) {
+		t.Errorf("Digest = %q, want %q", got, Digest(body))
+	}
+	keyID, err := SignatureKeyID(req)
+	if err != nil || keyID != testKeyID {
+		t.Errorf("SignatureKeyID = %q, %v", keyID, err)
+	}
+	if KeyOwner(keyID) != "https://a.example/ap/users/1" {
+		t.Errorf("KeyOwner = %q", KeyOwner(keyID))
+	}
+	if err := Verify(req, body, &key.PublicKey); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+
+	get := httptest.NewRequest(http.MethodGet, "https://b.example/ap/users/2", nil)
+	if err := Sign(get, nil, testKeyID, key); err != nil {
+		t.Fatal(err)
+	}
+	if get.Header.Get("Digest") != "" {
+		t.Error("GET without a body got a Digest header")
+	}
+	if err := Verify(get, nil, &key.PublicKey); err != nil {
+		t.Errorf("Verify GET: %v", err)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	key := testKey(t)
+	other := testKey(t)
+	body := []byte(`{"type":"Like"}`)
+	all := []string{"(request-target)", "host", "date", "digest"}
+
+	tests := []struct {
+		name   string
+		tamper func(req *http.Request) []byte
+	}{
+		{"wrong key", func(req *http.Request) []byte {
+			signAt(t, req, body, other, time.Now(), all)
+			return body
+		}},
+		{"body changed", func(req *http.Request) []byte {
+			signAt(t, req, body, key, time.Now(), all)
+			return []byte(`{"type":"Delete"}`)
+		}},
+		{"digest header changed to match", func(req *http.Request) []byte {
+			signAt(t, req, body, key, time.Now(), all)
+			changed := []byte(`{"type":"Delete"}`)
+			req.Header.Set("Digest", Digest(changed))
+			return changed
+		}},
+		{"target changed", func(req *http.Request) []byte {
+			signAt(t, req, body, key, time.Now(), all)
+			req.URL.Path = "/ap/users/3/inbox"
+			return body
+		}},
+		{"host changed", func(req *http.Request) []byte {
+			signAt(t, req, body, key, time.Now(), all)
+			req.Host = "c.example"
+			return body
+		}},
+		{"digest not signed", func(req *http.Request) []byte {
+			signAt(t, req, body, key, time.Now(), []string{"(request-target)", "host", "date"})
+			return body
+		}},
+		{"date not signed", func(req *http.Request) []byte {
+			signAt(t, req, body, key, time.Now(), []string{"(request-target)", "host", "digest"})
+			return body
+		}},
+		{"date too old", func(req *http.Request) []byte {
+			signAt(t, req, body, key, time.Now().Add(-MaxClockSkew-time.Minute), all)
+			return body
+		}},
+		{"date too far ahead", func(req *http.Request) []byte {
+			signAt(t, req, body, key, time.Now().Add(MaxClockSkew+time.Minute), all)
+			return body
+		}},
+		{"no signature", func(req *http.Request) []byte {
+			return body
+		}},
+		{"unsupported algorithm", func(req *http.Request) []byte {
+			signAt(t, req, body, key, time.Now(), all)
+			req.Header.Set("Signature", strings.Replace(req.Header.Get("Signature"), "rsa-sha256", "hmac-sha256", 1))
+			return body
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newInboxRequest(body)
+			received := tt.tamper(req)
+			err := Verify(req, received, &key.PublicKey)
+			if !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("err = %v, want ErrInvalidSignature", err)
+			}
+		})
+	}
+}
+
+func TestVerifyAllowsClockSkew(t *testing.T) {
+	key := testKey(t)
+	body := []byte(`{}`)
+	all := []string{"(request-target)", "host", "date", "digest"}
+	for _, offset := range []time.Duration{-MaxClockSkew + time.Minute, MaxClockSkew - time.Minute} {
+		req := newInboxRequest(body)
+		signAt(t, req, body, key, time.Now().Add(offset), all)
+		if err := Verify(req, body, &key.PublicKey); err != nil {
+			t.Errorf("clock off by %v: %v", offset, err)
+		}
+	}
+}
+
+func TestParsePublicKeyFormats(t *testing.T) {
+	privatePEM, publicPEM, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ParsePrivateKey(privatePEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkix, err := ParsePublicKey(publicPEM)
+	if err != nil {
+		t.Fatalf("PKIX key: %v", err)
+	}
+	if !pkix.Equal(&key.PublicKey) {
+		t.Error("PKIX key doesn't match the private key")
+	}
+
+	// Some servers publish the older PKCS #1 form.
+	pkcs1 := string(pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PUBLIC KEY",
+		Bytes: x509.MarshalPKCS1PublicKey(&key.PublicKey),
+	}))
+	parsed, err := ParsePublicKey(pkcs1)
+	if err != nil {
+		t.Fatalf("PKCS #1 key: %v", err)
+	}
+	if !parsed.Equal(&key.PublicKey) {
+		t.Error("PKCS #1 key doesn't match the private key")
+	}
+
+	if _, err := ParsePublicKey("not a key"); err == nil {
+		t.Error("expected an error for garbage")
+	}
+}
+
+func TestVerifyRequest(t *testing.T) {
+	key := testKey(t)
+	other := testKey(t)
+	body := []byte(`{"type":"Follow"}`)
+	all := []string{"(request-target)", "host", "date", "digest"}
+	publicPEM := func(k *rsa.PrivateKey) string {
+		der, err := x509.MarshalPKIXPublicKey(&k.PublicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	}
+	owner := KeyOwner(testKeyID)
+	current := PublicKey{ID: testKeyID, Owner: owner, PublicKeyPem: publicPEM(key)}
+	stale := PublicKey{ID: testKeyID, Owner: owner, PublicKeyPem: publicPEM(other)}
+
+	tests := []struct {
+		name     string
+		cached   PublicKey
+		fresh    PublicKey
+		fetchErr error
+		want     string
+		fetches  int
+	}{
+		{"cached key", current, current, nil, owner, 0},
+		{"rotated key is refetched", stale, current, nil, owner, 1},
+		{"still wrong after refetch", stale, stale, nil, "", 1},
+		{"unknown key id", PublicKey{ID: owner + "#other"}, PublicKey{ID: owner + "#other"}, nil, "", 1},
+		{"fetch fails", stale, PublicKey{}, errors.New("connection refused"), "", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newInboxRequest(body)
+			signAt(t, req, body, key, time.Now(), all)
+			fetches := 0
+			lookup := func(ctx context.Context, actorID string, refresh bool) (PublicKey, error) {
+				if actorID != owner {
+					t.Errorf("looked up %s, want %s", actorID, owner)
+				}
+				if !refresh {
+					return tt.cached, nil
+				}
+				fetches++
+				return tt.fresh, tt.fetchErr
+			}
+			got, err := VerifyRequest(req, body, lookup)
+			if got != tt.want || (err == nil) != (tt.want != "") {
+				t.Errorf("VerifyRequest = %q, %v; want %q", got, err, tt.want)
+			}
+			if tt.fetchErr != nil && !errors.Is(err, tt.fetchErr) {
+				t.Errorf("err = %v, want the lookup's error", err)
+			}
+			if fetches != tt.fetches {
+				t.Errorf("refetched %d times, want %d", fetches, tt.fetches)
+			}
+		})
+	}
+
+	unsigned := newInboxRequest(body)
+	if _, err := VerifyRequest(unsigned, body, func(context.Context, string, bool) (PublicKey, error) {
+		t.Error("looked up a key for an unsigned request")
+		return PublicKey{}, nil
+	}); err == nil {
+		t.Error("unsigned request: expected an error")
+	}
+}
@@ -0,0 +1,139 @@
+// Package activitypub holds the small slice of ActivityPub, WebFinger and
+// HTTP Signatures that Chirpy needs to federate users and chirps.
+package activitypub
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	// ContentType is what we serve and send. We also accept the JSON-LD
+	// form when reading.
+	ContentType   = "application/activity+json"
+	LDContentType = `application/ld+json; profile="https://www.w3.org/ns/activitystreams"`
+	Context       = "https://www.w3.org/ns/activitystreams"
+	SecurityV1    = "https://w3id.org/security/v1"
+	// Public is the special collection that addresses everyone.
+	Public = "https://www.w3.org/ns/activitystreams#Public"
+)
+
+type PublicKey struct {
+	ID           string `json:"id"`
+	Owner        string `json:"owner"`
+	PublicKeyPem string `json:"publicKeyPem"`
+}
+
+type Endpoints struct {
+	SharedInbox string `json:"sharedInbox,omitempty"`
+}
+
+type Actor struct {
+	Context           any        `json:"@context,omitempty"`
+	ID                string     `json:"id"`
+	Type              string     `json:"type"`
+	PreferredUsername string     `json:"preferredUsername"`
+	Name              string     `json:"name,omitempty"`
+	Inbox             string     `json:"inbox"`
+	Outbox            string     `json:"outbox,omitempty"`
+	Followers         string     `json:"followers,omitempty"`
+	Following         string     `json:"following,omitempty"`
+	URL               string     `json:"url,omitempty"`
+	Endpoints         *Endpoints `json:"endpoints,omitempty"`
+	PublicKey         PublicKey  `json:"publicKey"`
+}
+
+type Document struct {
+	Type      string `json:"type"`
+	MediaType string `json:"mediaType"`
+	URL       string `json:"url"`
+	Name      string `json:"name,omitempty"`
+	Blurhash  string `json:"blurhash,omitempty"`
+	Width     int    `json:"width,omitempty"`
+	Height    int    `json:"height,omitempty"`
+}
+
+type Note struct {
+	Context      any        `json:"@context,omitempty"`
+	ID           string     `json:"id"`
+	Type         string     `json:"type"`
+	AttributedTo string     `json:"attributedTo"`
+	Content      string     `json:"content"`
+	Summary      string     `json:"summary,omitempty"`
+	Sensitive    bool       `json:"sensitive"`
+	Published    time.Time  `json:"published"`
+	URL          string     `json:"url,omitempty"`
+	To           []string   `json:"to"`
+	Cc           []string   `json:"cc"`
+	Attachment   []Document `json:"attachment,omitempty"`
+}
+
+// Activity is an incoming or outgoing activity. Object is kept raw since
+// it may be a bare ID or an embedded object.
+type Activity struct {
+	Context any             `json:"@context,omitempty"`
+	ID      string          `json:"id"`
+	Type    string          `json:"type"`
+	Actor   string          `json:"actor"`
+	Object  json.RawMessage `json:"object"`
+	To      []string        `json:"to,omitempty"`
+	Cc      []string        `json:"cc,omitempty"`
+}
+
+// ObjectRef is the part of an embedded object we look at.
+type ObjectRef struct {
+	ID     string          `json:"id"`
+	Type   string          `json:"type"`
+	Actor  string          `json:"actor"`
+	Object json.RawMessage `json:"object"`
+}
+
+// ParseObject reads an activity's object, which may be a bare ID or an
+// embedded object.
+func ParseObject(raw json.RawMessage) (ObjectRef, error) {
+	var id string
+	if err := json.Unmarshal(raw, &id); err == nil {
+		return ObjectRef{ID: id}, nil
+	}
+	var ref ObjectRef
+	err := json.Unmarshal(raw, &ref)
+	return ref, err
+}
+
+// NewActivity wraps an object in an activity of the given type.
+func NewActivity(id, typ, actor string, object any, to, cc []string) (Activity, error) {
+	raw, err := json.Marshal(object)
+	if err != nil {
+		return Activity{}, err
+	}
+	return Activity{
+		Context: Context,
+		ID:      id,
+		Type:    typ,
+		Actor:   actor,
+		Object:  raw,
+		To:      to,
+		Cc:      cc,
+	}, nil
+}
+
+type OrderedCollection struct {
+	Context      any    `json:"@context,omitempty"`
+	ID           string `json:"id"`
+	Type         string `json:"type"`
+	TotalItems   int64  `json:"totalItems"`
+	OrderedItems []any  `json:"orderedItems,omitempty"`
+}
+
+// WebFinger is a JSON Resource Descriptor.
+type WebFinger struct {
+	Subject string          `json:"subject"`
+	Aliases []string        `json:"aliases,omitempty"`
+	Links   []WebFingerLink `json:"links"`
+}
+
+type WebFingerLink struct {
+	Rel  string `json:"rel"`
+	Type string `json:"type,omitempty"`
+	Href string `json:"href,omitempty"`
+}
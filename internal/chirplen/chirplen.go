@@ -0,0 +1,28 @@
+package chirplen
+
+import (
+	"regexp"
+
+	"github.com/rivo/uniseg"
+)
+
+// DefaultURLWeight is how many characters a link counts as, however long
+// the URL actually is.
+const DefaultURLWeight = 23
+
+var urlPattern = regexp.MustCompile(`https?://[^\s]+`)
+
+// Count returns the length of text in user-perceived characters (grapheme
+// clusters), so an emoji with skin tone or a letter with combining accents
+// counts once. Each URL counts as urlWeight characters.
+func Count(text string, urlWeight int) int {
+	length := 0
+	last := 0
+	for _, loc := range urlPattern.FindAllStringIndex(text, -1) {
+		length += uniseg.GraphemeClusterCount(text[last:loc[0]])
+		length += urlWeight
+		last = loc[1]
+	}
+	length += uniseg.GraphemeClusterCount(text[last:])
+	return length
+}
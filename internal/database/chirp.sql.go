@@ -7,29 +7,36 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps(id, created_at, updated_at, body, user_id)
+INSERT INTO chirps(id, created_at, updated_at, body, user_id, visibility, content_warning, sensitive)
 VALUES (
     $1,
     $2,
     $3,
     $4,
-    $5
+    $5,
+    $6,
+    $7,
+    $8
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, hidden_at, visibility, content_warning, sensitive
 `
 
 type CreateChirpParams struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	Body      string
-	UserID    uuid.UUID
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Body           string
+	UserID         uuid.UUID
+	Visibility     string
+	ContentWarning string
+	Sensitive      bool
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
@@ -39,6 +46,9 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		arg.UpdatedAt,
 		arg.Body,
 		arg.UserID,
+		arg.Visibility,
+		arg.ContentWarning,
+		arg.Sensitive,
 	)
 	var i Chirp
 	err := row.Scan(
@@ -47,6 +57,10 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.HiddenAt,
+		&i.Visibility,
+		&i.ContentWarning,
+		&i.Sensitive,
 	)
 	return i, err
 }
@@ -67,7 +81,7 @@ func (q *Queries) DeleteChirp(ctx context.Context, arg DeleteChirpParams) error
 }
 
 const getAllChirps = `-- name: GetAllChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps
+SELECT id, created_at, updated_at, body, user_id, hidden_at, visibility, content_warning, sensitive FROM chirps
 ORDER BY created_at ASC
 `
 
@@ -86,6 +100,10 @@ func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.HiddenAt,
+			&i.Visibility,
+			&i.ContentWarning,
+			&i.Sensitive,
 		); err != nil {
 			return nil, err
 		}
@@ -101,7 +119,7 @@ func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
 }
 
 const getChirp = `-- name: GetChirp :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps
+SELECT id, created_at, updated_at, body, user_id, hidden_at, visibility, content_warning, sensitive FROM chirps
 WHERE id = $1
 `
 
@@ -114,12 +132,16 @@ func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.HiddenAt,
+		&i.Visibility,
+		&i.ContentWarning,
+		&i.Sensitive,
 	)
 	return i, err
 }
 
 const getChirpsForUser = `-- name: GetChirpsForUser :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps
+SELECT id, created_at, updated_at, body, user_id, hidden_at, visibility, content_warning, sensitive FROM chirps
 WHERE user_id = $1
 ORDER BY created_at ASC
 `
@@ -139,6 +161,10 @@ func (q *Queries) GetChirpsForUser(ctx context.Context, userID uuid.UUID) ([]Chi
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.HiddenAt,
+			&i.Visibility,
+			&i.ContentWarning,
+			&i.Sensitive,
 		); err != nil {
 			return nil, err
 		}
@@ -153,11 +179,88 @@ func (q *Queries) GetChirpsForUser(ctx context.Context, userID uuid.UUID) ([]Chi
 	return items, nil
 }
 
+const isChirpDeleted = `-- name: IsChirpDeleted :one
+SELECT EXISTS (
+    SELECT 1 FROM deleted_chirps
+    WHERE id = $1
+)
+`
+
+func (q *Queries) IsChirpDeleted(ctx context.Context, id uuid.UUID) (bool, error) {
+	row := q.db.QueryRowContext(ctx, isChirpDeleted, id)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const recordDeletedChirp = `-- name: RecordDeletedChirp :exec
+INSERT INTO deleted_chirps(id, deleted_at)
+VALUES ($1, $2)
+ON CONFLICT (id) DO NOTHING
+`
+
+type RecordDeletedChirpParams struct {
+	ID        uuid.UUID
+	DeletedAt time.Time
+}
+
+func (q *Queries) RecordDeletedChirp(ctx context.Context, arg RecordDeletedChirpParams) error {
+	_, err := q.db.ExecContext(ctx, recordDeletedChirp, arg.ID, arg.DeletedAt)
+	return err
+}
+
+const removeChirp = `-- name: RemoveChirp :exec
+DELETE FROM chirps
+WHERE id = $1
+`
+
+func (q *Queries) RemoveChirp(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, removeChirp, id)
+	return err
+}
+
 const resetChirps = `-- name: ResetChirps :exec
-TRUNCATE chirps
+TRUNCATE chirps CASCADE
 `
 
 func (q *Queries) ResetChirps(ctx context.Context) error {
 	_, err := q.db.ExecContext(ctx, resetChirps)
 	return err
 }
+
+const setChirpHidden = `-- name: SetChirpHidden :exec
+UPDATE chirps
+SET hidden_at = $2,
+updated_at = $3
+WHERE id = $1
+`
+
+type SetChirpHiddenParams struct {
+	ID        uuid.UUID
+	HiddenAt  sql.NullTime
+	UpdatedAt time.Time
+}
+
+func (q *Queries) SetChirpHidden(ctx context.Context, arg SetChirpHiddenParams) error {
+	_, err := q.db.ExecContext(ctx, setChirpHidden, arg.ID, arg.HiddenAt, arg.UpdatedAt)
+	return err
+}
+
+const setChirpSensitive = `-- name: SetChirpSensitive :exec
+UPDATE chirps
+SET sensitive = true,
+content_warning = $2,
+updated_at = $3
+WHERE id = $1
+`
+
+type SetChirpSensitiveParams struct {
+	ID             uuid.UUID
+	ContentWarning string
+	UpdatedAt      time.Time
+}
+
+func (q *Queries) SetChirpSensitive(ctx context.Context, arg SetChirpSensitiveParams) error {
+	_, err := q.db.ExecContext(ctx, setChirpSensitive, arg.ID, arg.ContentWarning, arg.UpdatedAt)
+	return err
+}
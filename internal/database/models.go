@@ -11,12 +11,213 @@ import (
 	"github.com/google/uuid"
 )
 
+type ActorKey struct {
+	UserID        uuid.UUID
+	CreatedAt     time.Time
+	PrivateKeyPem string
+	PublicKeyPem  string
+}
+
+type AnalyticsSeen struct {
+	Kind      string
+	SubjectID uuid.UUID
+	Viewer    string
+	Day       time.Time
+}
+
+type Bookmark struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+}
+
 type Chirp struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Body           string
+	UserID         uuid.UUID
+	HiddenAt       sql.NullTime
+	Visibility     string
+	ContentWarning string
+	Sensitive      bool
+}
+
+type ChirpStatsDaily struct {
+	ChirpID     uuid.UUID
+	Day         time.Time
+	Impressions int32
+	Engagements int32
+}
+
+type Conversation struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
-	Body      string
+	CreatedBy uuid.UUID
+}
+
+type ConversationMember struct {
+	ConversationID uuid.UUID
+	UserID         uuid.UUID
+	JoinedAt       time.Time
+	LastReadAt     sql.NullTime
+}
+
+type DeletedChirp struct {
+	ID        uuid.UUID
+	DeletedAt time.Time
+}
+
+type Draft struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	UserID         uuid.UUID
+	Body           string
+	PublishAt      sql.NullTime
+	Visibility     string
+	ContentWarning string
+	Sensitive      bool
+}
+
+type FederationDelivery struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	SenderID      uuid.UUID
+	InboxUrl      string
+	Payload       string
+	Attempts      int32
+	NextAttemptAt time.Time
+}
+
+type FilterRule struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	Pattern   string
+	Kind      string
+	Action    string
+	Reason    string
+}
+
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type LinkPreview struct {
+	Url         string
+	FetchedAt   time.Time
+	Ok          bool
+	Title       string
+	Description string
+	ImageUrl    string
+	SiteName    string
+}
+
+type List struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	OwnerID   uuid.UUID
+	Name      string
+	IsPrivate bool
+}
+
+type ListMember struct {
+	ListID    uuid.UUID
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type MediaFile struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UserID      uuid.UUID
+	StorageKey  string
+	ContentType string
+	SizeBytes   int64
+	ChirpID     uuid.NullUUID
+	Position    int32
+	Width       sql.NullInt32
+	Height      sql.NullInt32
+	Blurhash    sql.NullString
+	ProcessedAt sql.NullTime
+}
+
+type MediaVariant struct {
+	MediaID     uuid.UUID
+	Name        string
+	StorageKey  string
+	ContentType string
+	Width       int32
+	Height      int32
+	SizeBytes   int64
+}
+
+type Message struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	ConversationID uuid.UUID
+	SenderID       uuid.UUID
+	Body           string
+}
+
+type ModerationAction struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	ModeratorID  uuid.UUID
+	Action       string
+	ReportID     uuid.NullUUID
+	ChirpID      uuid.NullUUID
+	TargetUserID uuid.NullUUID
+	Note         string
+}
+
+type MutedWord struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	Word      string
+	ExpiresAt sql.NullTime
+}
+
+type Notification struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
 	UserID    uuid.UUID
+	Kind      string
+	ChirpID   uuid.NullUUID
+	ReadAt    sql.NullTime
+}
+
+type PinnedChirp struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type Poll struct {
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+	Multiple  bool
+	ClosesAt  time.Time
+	ClosedAt  sql.NullTime
+}
+
+type PollOption struct {
+	ChirpID  uuid.UUID
+	Position int32
+	Title    string
+}
+
+type PollVote struct {
+	ChirpID   uuid.UUID
+	UserID    uuid.UUID
+	CreatedAt time.Time
+	Choices   []int32
 }
 
 type RefreshToken struct {
@@ -28,11 +229,120 @@ type RefreshToken struct {
 	RevokedAt sql.NullTime
 }
 
+type RemoteActor struct {
+	ID             string
+	FetchedAt      time.Time
+	InboxUrl       string
+	SharedInboxUrl string
+	PublicKeyID    string
+	PublicKeyPem   string
+}
+
+type RemoteFollow struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UserID     uuid.UUID
+	ActorID    string
+	AcceptedAt sql.NullTime
+}
+
+type RemoteFollower struct {
+	UserID     uuid.UUID
+	ActorID    string
+	ActivityID string
+	CreatedAt  time.Time
+}
+
+type RemoteLike struct {
+	ChirpID    uuid.UUID
+	ActorID    string
+	ActivityID string
+	CreatedAt  time.Time
+}
+
+type Report struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	ReporterID uuid.NullUUID
+	ChirpID    uuid.NullUUID
+	AuthorID   uuid.UUID
+	Reason     string
+	Details    string
+	Status     string
+}
+
+type Subscription struct {
+	UserID             uuid.UUID
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+	Plan               string
+	Status             string
+	CurrentPeriodStart time.Time
+	CurrentPeriodEnd   time.Time
+	GraceUntil         sql.NullTime
+	CanceledAt         sql.NullTime
+}
+
 type User struct {
-	ID             uuid.UUID
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
-	Email          string
-	HashedPassword string
-	IsChirpyRed    bool
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Email           string
+	HashedPassword  string
+	IsChirpyRed     bool
+	IsModerator     bool
+	SuspendedAt     sql.NullTime
+	ExpandSensitive bool
+}
+
+type UserBlock struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+	CreatedAt time.Time
+}
+
+type UserMute struct {
+	MuterID   uuid.UUID
+	MutedID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type UserStatsDaily struct {
+	UserID        uuid.UUID
+	Day           time.Time
+	ProfileVisits int32
+}
+
+type WebhookDelivery struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	EndpointID    uuid.UUID
+	EventID       uuid.UUID
+	Event         string
+	Payload       string
+	Status        string
+	Attempts      int32
+	NextAttemptAt time.Time
+	ResponseCode  int32
+	LastError     string
+	CompletedAt   sql.NullTime
+}
+
+type WebhookEndpoint struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	UserID       uuid.UUID
+	Url          string
+	Secret       string
+	Events       []string
+	FailureCount int32
+	DisabledAt   sql.NullTime
+}
+
+type WebhookEvent struct {
+	ID         string
+	Event      string
+	ReceivedAt time.Time
 }
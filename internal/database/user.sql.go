@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -46,7 +47,7 @@ VALUES (
     $4,
     $5
 )
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, is_moderator, suspended_at, expand_sensitive
 `
 
 type CreateUserParams struct {
@@ -73,6 +74,9 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.IsModerator,
+		&i.SuspendedAt,
+		&i.ExpandSensitive,
 	)
 	return i, err
 }
@@ -117,8 +121,30 @@ func (q *Queries) GetUser(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+const getUserByID = `-- name: GetUserByID :one
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, is_moderator, suspended_at, expand_sensitive FROM users
+WHERE id = $1
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.IsModerator,
+		&i.SuspendedAt,
+		&i.ExpandSensitive,
+	)
+	return i, err
+}
+
 const resetUsers = `-- name: ResetUsers :exec
-TRUNCATE refresh_tokens, chirps, users
+TRUNCATE refresh_tokens, chirps, users CASCADE
 `
 
 func (q *Queries) ResetUsers(ctx context.Context) error {
@@ -126,6 +152,42 @@ func (q *Queries) ResetUsers(ctx context.Context) error {
 	return err
 }
 
+const setExpandSensitive = `-- name: SetExpandSensitive :exec
+UPDATE users
+SET expand_sensitive = $2,
+updated_at = $3
+WHERE id = $1
+`
+
+type SetExpandSensitiveParams struct {
+	ID              uuid.UUID
+	ExpandSensitive bool
+	UpdatedAt       time.Time
+}
+
+func (q *Queries) SetExpandSensitive(ctx context.Context, arg SetExpandSensitiveParams) error {
+	_, err := q.db.ExecContext(ctx, setExpandSensitive, arg.ID, arg.ExpandSensitive, arg.UpdatedAt)
+	return err
+}
+
+const setUserSuspended = `-- name: SetUserSuspended :exec
+UPDATE users
+SET suspended_at = $2,
+updated_at = $3
+WHERE id = $1
+`
+
+type SetUserSuspendedParams struct {
+	ID          uuid.UUID
+	SuspendedAt sql.NullTime
+	UpdatedAt   time.Time
+}
+
+func (q *Queries) SetUserSuspended(ctx context.Context, arg SetUserSuspendedParams) error {
+	_, err := q.db.ExecContext(ctx, setUserSuspended, arg.ID, arg.SuspendedAt, arg.UpdatedAt)
+	return err
+}
+
 const upgradeUser = `-- name: UpgradeUser :exec
 UPDATE users
 SET is_chirpy_red = $2,
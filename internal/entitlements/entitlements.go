@@ -0,0 +1,106 @@
+// Package entitlements maps account tiers to what accounts on them can do.
+//
+// Tiers are plain data so limits can change without a code change. A tier
+// file is a JSON object keyed by tier name:
+//
+//	{
+//	  "free": {"max_chirp_length": 140, "max_media": 4, "scheduled_posts": true},
+//	  "red":  {"max_chirp_length": 280, "max_media": 8, "scheduled_posts": true,
+//	           "analytics": true}
+//	}
+//
+// Features left out of a tier are off.
+package entitlements
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+type Tier string
+
+const (
+	Free Tier = "free"
+	Red  Tier = "red"
+)
+
+// Feature is an on/off capability of a tier.
+type Feature string
+
+const (
+	ScheduledPosts Feature = "scheduled_posts"
+	Analytics      Feature = "analytics"
+)
+
+// Limits are what one tier allows.
+type Limits struct {
+	MaxChirpLength int  `json:"max_chirp_length"`
+	MaxMedia       int  `json:"max_media"`
+	ScheduledPosts bool `json:"scheduled_posts"`
+	Analytics      bool `json:"analytics"`
+}
+
+// Allows reports whether the tier has feature.
+func (l Limits) Allows(feature Feature) bool {
+	switch feature {
+	case ScheduledPosts:
+		return l.ScheduledPosts
+	case Analytics:
+		return l.Analytics
+	}
+	return false
+}
+
+// Config holds the limits of every tier.
+type Config map[Tier]Limits
+
+// Default is the configuration used when no tier file is given. Free
+// accounts keep everything they could do before tiers existed.
+func Default() Config {
+	return Config{
+		Free: {
+			MaxChirpLength: 140,
+			MaxMedia:       4,
+			ScheduledPosts: true,
+		},
+		Red: {
+			MaxChirpLength: 280,
+			MaxMedia:       8,
+			ScheduledPosts: true,
+			Analytics:      true,
+		},
+	}
+}
+
+// Load reads a tier file. Every tier must be present.
+func Load(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := Config{}
+	err = json.Unmarshal(data, &c)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	for _, tier := range []Tier{Free, Red} {
+		l, ok := c[tier]
+		if !ok {
+			return nil, fmt.Errorf("%s: missing tier %q", path, tier)
+		}
+		if l.MaxChirpLength <= 0 || l.MaxMedia < 0 {
+			return nil, fmt.Errorf("%s: invalid limits for tier %q", path, tier)
+		}
+	}
+	return c, nil
+}
+
+// For returns the limits of tier, or of the free tier when tier isn't
+// configured.
+func (c Config) For(tier Tier) Limits {
+	if l, ok := c[tier]; ok {
+		return l
+	}
+	return c[Free]
+}
@@ -0,0 +1,469 @@
+package filter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+type Kind string
+
+const (
+	KindWord     Kind = "word"
+	KindWildcard Kind = "wildcard"
+	KindRegex    Kind = "regex"
+)
+
+type Action string
+
+const (
+	ActionMask   Action = "mask"
+	ActionReject Action = "reject"
+	ActionFlag   Action = "flag"
+)
+
+const mask = "****"
+
+// Rule is one entry in the filter. Word and wildcard patterns are matched
+// against each whitespace separated word, and each part of a hyphenated
+// word, after it has been normalized, so "Kerfuffle!", "KERFUFFLE",
+// "k3rfuffl3" and "kerfuffle's" all match the word rule "kerfuffle". Regex patterns are
+// matched case-insensitively against the whole text, after compatibility
+// folding and accent stripping but with punctuation kept, and with runs of
+// whitespace collapsed to a single space. An empty action masks.
+type Rule struct {
+	Pattern string `json:"pattern"`
+	Kind    Kind   `json:"kind"`
+	Action  Action `json:"action"`
+	Reason  string `json:"reason"`
+}
+
+// Source loads the current rule set.
+type Source func(ctx context.Context) ([]Rule, error)
+
+// FileSource reads rules from a JSON file holding an array of rules.
+func FileSource(path string) Source {
+	return func(ctx context.Context) ([]Rule, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		rules := []Rule{}
+		err = json.Unmarshal(data, &rules)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+		return rules, nil
+	}
+}
+
+type compiledRule struct {
+	Rule
+	word string
+	re   *regexp.Regexp
+}
+
+func (c compiledRule) matches(candidates []string) bool {
+	for _, candidate := range candidates {
+		if c.re != nil {
+			if c.re.MatchString(candidate) {
+				return true
+			}
+		} else if candidate == c.word {
+			return true
+		}
+	}
+	return false
+}
+
+func compile(rule Rule) (compiledRule, error) {
+	c := compiledRule{Rule: rule}
+	switch rule.Action {
+	case "":
+		c.Action = ActionMask
+	case ActionMask, ActionReject, ActionFlag:
+	default:
+		return c, fmt.Errorf("rule %q: unknown action %q", rule.Pattern, rule.Action)
+	}
+
+	switch rule.Kind {
+	case KindWord, "":
+		c.Kind = KindWord
+		c.word = fold(rule.Pattern, '\x00')
+		if c.word == "" {
+			return c, fmt.Errorf("rule %q: pattern is empty after normalization", rule.Pattern)
+		}
+	case KindWildcard:
+		// Normalize the literal parts and turn * and ? into their regex
+		// equivalents, anchored to the whole word.
+		var expr, literal strings.Builder
+		expr.WriteString("^")
+		flush := func() {
+			expr.WriteString(regexp.QuoteMeta(fold(literal.String(), '\x00')))
+			literal.Reset()
+		}
+		for _, r := range rule.Pattern {
+			switch r {
+			case '*':
+				flush()
+				expr.WriteString(".*")
+			case '?':
+				flush()
+				expr.WriteString(".")
+			default:
+				literal.WriteRune(r)
+			}
+		}
+		flush()
+		expr.WriteString("$")
+		re, err := regexp.Compile(expr.String())
+		if err != nil {
+			return c, fmt.Errorf("rule %q: %w", rule.Pattern, err)
+		}
+		c.re = re
+	case KindRegex:
+		re, err := regexp.Compile("(?i)" + rule.Pattern)
+		if err != nil {
+			return c, fmt.Errorf("rule %q: %w", rule.Pattern, err)
+		}
+		c.re = re
+	default:
+		return c, fmt.Errorf("rule %q: unknown kind %q", rule.Pattern, rule.Kind)
+	}
+	return c, nil
+}
+
+// Result describes what the filter did to a piece of text.
+type Result struct {
+	Text     string
+	Rejected bool
+	Flagged  bool
+	Reasons  []string
+}
+
+// Engine applies a rule set and can swap it for a fresh one at runtime.
+type Engine struct {
+	source Source
+
+	mu    sync.RWMutex
+	rules []compiledRule
+}
+
+func New(source Source) *Engine {
+	return &Engine{source: source}
+}
+
+// Reload fetches rules from the source and replaces the active set. If any
+// rule fails to compile the previous set stays in place.
+func (e *Engine) Reload(ctx context.Context) error {
+	rules, err := e.source(ctx)
+	if err != nil {
+		return err
+	}
+	compiled := make([]compiledRule, 0, len(rules))
+	for _, rule := range rules {
+		c, err := compile(rule)
+		if err != nil {
+			return err
+		}
+		compiled = append(compiled, c)
+	}
+
+	e.mu.Lock()
+	e.rules = compiled
+	e.mu.Unlock()
+	return nil
+}
+
+// Rules returns the active rule set.
+func (e *Engine) Rules() []Rule {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	rules := make([]Rule, 0, len(e.rules))
+	for _, c := range e.rules {
+		rules = append(rules, c.Rule)
+	}
+	return rules
+}
+
+// word is one whitespace separated word of the text being filtered.
+type word struct {
+	text       string
+	candidates []string
+	parts      []wordPart
+	// normStart and normEnd locate the word in the normalized text that
+	// regex rules run against.
+	normStart, normEnd int
+
+	masked      bool
+	maskedParts map[int]bool
+}
+
+// wordPart is one piece of a hyphenated word.
+type wordPart struct {
+	text       string
+	candidates []string
+}
+
+// splitWords breaks text into words, keeping the whitespace between them,
+// and builds the normalized text for regex rules.
+func splitWords(text string) ([]*word, []string, string) {
+	var words []*word
+	var spaces []string
+	var norm strings.Builder
+	for {
+		start := strings.IndexFunc(text, func(r rune) bool { return !unicode.IsSpace(r) })
+		if start < 0 {
+			spaces = append(spaces, text)
+			break
+		}
+		spaces = append(spaces, text[:start])
+		text = text[start:]
+		end := strings.IndexFunc(text, unicode.IsSpace)
+		if end < 0 {
+			end = len(text)
+		}
+		w := &word{text: text[:end], candidates: Candidates(text[:end])}
+		text = text[end:]
+
+		if parts := strings.FieldsFunc(w.text, isHyphen); len(parts) > 1 {
+			for _, part := range parts {
+				w.parts = append(w.parts, wordPart{text: part, candidates: Candidates(part)})
+			}
+		}
+		if len(words) > 0 {
+			norm.WriteByte(' ')
+		}
+		w.normStart = norm.Len()
+		norm.WriteString(normalize(w.text))
+		w.normEnd = norm.Len()
+		words = append(words, w)
+	}
+	return words, spaces, norm.String()
+}
+
+func isHyphen(r rune) bool {
+	return r == '-' || unicode.Is(unicode.Pd, r)
+}
+
+// Apply runs text through every rule. Masked words keep any leading or
+// trailing punctuation, so "Kerfuffle!" becomes "****!", and a match on
+// one part of a hyphenated word masks only that part.
+func (e *Engine) Apply(text string) Result {
+	e.mu.RLock()
+	rules := e.rules
+	e.mu.RUnlock()
+
+	words, spaces, norm := splitWords(text)
+	result := Result{}
+	for _, rule := range rules {
+		matched := false
+		if rule.Kind == KindRegex {
+			for _, loc := range rule.re.FindAllStringIndex(norm, -1) {
+				if loc[0] == loc[1] {
+					continue
+				}
+				matched = true
+				if rule.Action != ActionMask {
+					break
+				}
+				for _, w := range words {
+					if w.normStart < loc[1] && loc[0] < w.normEnd {
+						w.masked = true
+					}
+				}
+			}
+		} else {
+			for _, w := range words {
+				if rule.matches(w.candidates) {
+					matched = true
+					w.masked = w.masked || rule.Action == ActionMask
+					continue
+				}
+				for i, part := range w.parts {
+					if !rule.matches(part.candidates) {
+						continue
+					}
+					matched = true
+					if rule.Action == ActionMask {
+						if w.maskedParts == nil {
+							w.maskedParts = map[int]bool{}
+						}
+						w.maskedParts[i] = true
+					}
+				}
+			}
+		}
+		if !matched {
+			continue
+		}
+		switch rule.Action {
+		case ActionReject:
+			result.Rejected = true
+			result.Reasons = append(result.Reasons, rule.Reason)
+		case ActionFlag:
+			result.Flagged = true
+			result.Reasons = append(result.Reasons, rule.Reason)
+		}
+	}
+
+	var out strings.Builder
+	for i, w := range words {
+		out.WriteString(spaces[i])
+		out.WriteString(w.render())
+	}
+	out.WriteString(spaces[len(words)])
+	result.Text = out.String()
+	return result
+}
+
+// render returns the word with its masks applied.
+func (w *word) render() string {
+	if w.masked {
+		return maskWord(w.text)
+	}
+	if len(w.maskedParts) == 0 {
+		return w.text
+	}
+	var b strings.Builder
+	rest := w.text
+	for i, part := range w.parts {
+		at := strings.Index(rest, part.text)
+		b.WriteString(rest[:at])
+		if w.maskedParts[i] {
+			b.WriteString(maskWord(part.text))
+		} else {
+			b.WriteString(part.text)
+		}
+		rest = rest[at+len(part.text):]
+	}
+	b.WriteString(rest)
+	return b.String()
+}
+
+// maskWord masks the letters of word, keeping surrounding punctuation and
+// any possessive "'s".
+func maskWord(word string) string {
+	start := strings.IndexFunc(word, isWordRune)
+	if start < 0 {
+		return mask
+	}
+	if at := possessiveAt(word); at >= 0 {
+		return word[:start] + mask + word[at:]
+	}
+	end := strings.LastIndexFunc(word, isWordRune)
+	_, size := utf8.DecodeRuneInString(word[end:])
+	return word[:start] + mask + word[end+size:]
+}
+
+// possessiveSuffixes are the ways a possessive "'s" is written.
+var possessiveSuffixes = []string{"'s", "’s", "ʼs"}
+
+// possessiveAt returns where a possessive "'s" ending the letters of word
+// starts, or -1 if there is none.
+func possessiveAt(word string) int {
+	end := strings.LastIndexFunc(word, isWordRune)
+	if end < 0 {
+		return -1
+	}
+	core := word[:end+1]
+	for _, suffix := range possessiveSuffixes {
+		at := len(core) - len(suffix)
+		if at <= 0 || !strings.EqualFold(core[at:], suffix) {
+			continue
+		}
+		if strings.IndexFunc(core[:at], isWordRune) < 0 {
+			return -1
+		}
+		return at
+	}
+	return -1
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r) || r == '@' || r == '$' || r == '|'
+}
+
+// leet maps common character substitutions back to letters. 1 and | are
+// ambiguous between i and l, so they are folded both ways.
+var leet = map[rune]rune{
+	'0': 'o',
+	'3': 'e',
+	'4': 'a',
+	'5': 's',
+	'7': 't',
+	'8': 'b',
+	'9': 'g',
+	'@': 'a',
+	'$': 's',
+}
+
+var stripMarks = transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+
+// normalize applies compatibility folding (so fullwidth and stylised
+// letters become plain ones), lowercases, and drops accents.
+func normalize(s string) string {
+	s = norm.NFKC.String(s)
+	s = strings.ToLower(s)
+	out, _, err := transform.String(stripMarks, s)
+	if err != nil {
+		return s
+	}
+	return out
+}
+
+// fold normalizes s and keeps only letters and digits. When one is not
+// zero, ambiguous "1" and "|" characters fold to it and other leetspeak
+// characters fold to the letters they stand for.
+func fold(s string, one rune) string {
+	var b strings.Builder
+	for _, r := range normalize(s) {
+		if one != '\x00' {
+			if r == '1' || r == '|' {
+				b.WriteRune(one)
+				continue
+			}
+			if l, ok := leet[r]; ok {
+				b.WriteRune(l)
+				continue
+			}
+		}
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Candidates returns the normalized forms a word is matched under: with
+// punctuation stripped, and with leetspeak folded both ways. A possessive
+// word is also matched without its "'s".
+func Candidates(word string) []string {
+	forms := []string{word}
+	if at := possessiveAt(word); at >= 0 {
+		forms = append(forms, word[:at])
+	}
+	seen := map[string]bool{}
+	candidates := []string{}
+	for _, form := range forms {
+		for _, one := range []rune{'\x00', 'i', 'l'} {
+			c := fold(form, one)
+			if c == "" || seen[c] {
+				continue
+			}
+			seen[c] = true
+			candidates = append(candidates, c)
+		}
+	}
+	return candidates
+}
@@ -0,0 +1,142 @@
+package filter
+
+import (
+	"context"
+	"testing"
+)
+
+func newEngine(t *testing.T, rules ...Rule) *Engine {
+	t.Helper()
+	e := New(func(ctx context.Context) ([]Rule, error) { return rules, nil })
+	if err := e.Reload(context.Background()); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	return e
+}
+
+func TestApply(t *testing.T) {
+	word := Rule{Pattern: "kerfuffle", Kind: KindWord}
+	tests := []struct {
+		name string
+		rule Rule
+		text string
+		want string
+	}{
+		// Plain words and punctuation.
+		{"exact", word, "what a kerfuffle", "what a ****"},
+		{"uppercase", word, "KERFUFFLE", "****"},
+		{"trailing punctuation", word, "Kerfuffle!", "****!"},
+		{"surrounding punctuation", word, `"kerfuffle,"`, `"****,"`},
+		{"not a substring", word, "kerfuffles", "kerfuffles"},
+		{"longer word", word, "kerfufflement", "kerfufflement"},
+		{"whitespace kept", word, " a\tkerfuffle\n", " a\t****\n"},
+
+		// Possessives.
+		{"possessive", word, "the kerfuffle's end", "the ****'s end"},
+		{"possessive uppercase", word, "KERFUFFLE'S", "****'S"},
+		{"possessive curly apostrophe", word, "kerfuffle’s", "****’s"},
+		{"possessive with punctuation", word, "kerfuffle's!", "****'s!"},
+		{"possessive leetspeak", word, "k3rfuffl3's", "****'s"},
+		{"possessive in hyphenated word", word, "anti-kerfuffle's", "anti-****'s"},
+		{"plural possessive", word, "kerfuffles'", "kerfuffles'"},
+		{"other contraction", word, "kerfuffle'd", "kerfuffle'd"},
+
+		// NFKC folding and accents.
+		{"fullwidth", word, "ｋｅｒｆｕｆｆｌｅ", "****"},
+		{"mathematical letters", word, "𝐤𝐞𝐫𝐟𝐮𝐟𝐟𝐥𝐞", "****"},
+		{"accents", word, "kérfüfflé", "****"},
+		{"combining marks", word, "kérfuffle", "****"},
+		{"accented rule", Rule{Pattern: "café"}, "CAFE", "****"},
+
+		// Leetspeak.
+		{"digits", word, "k3rfuffl3", "****"},
+		{"symbols", Rule{Pattern: "sass"}, "$@$$", "****"},
+		{"one as l", word, "kerfuff1e", "****"},
+		{"one as i", Rule{Pattern: "fiddle"}, "f1ddle", "****"},
+		{"pipe as l", word, "kerfuff|e", "****"},
+		{"leetspeak rule not folded", Rule{Pattern: "b0rk"}, "bork", "bork"},
+
+		// Hyphenated words.
+		{"hyphenated part", word, "mega-kerfuffle", "mega-****"},
+		{"en dash part", word, "kerfuffle–ish", "****–ish"},
+		{"whole hyphenated rule", Rule{Pattern: "mega-kerfuffle"}, "mega-kerfuffle", "****"},
+
+		// Wildcards.
+		{"wildcard star", Rule{Pattern: "kerf*", Kind: KindWildcard}, "kerfuffles", "****"},
+		{"wildcard star matches none", Rule{Pattern: "kerf*", Kind: KindWildcard}, "kerf", "****"},
+		{"wildcard question", Rule{Pattern: "k?rfuffle", Kind: KindWildcard}, "korfuffle", "****"},
+		{"wildcard question one rune", Rule{Pattern: "k?rfuffle", Kind: KindWildcard}, "krfuffle", "krfuffle"},
+		{"wildcard anchored", Rule{Pattern: "fuff*", Kind: KindWildcard}, "kerfuffle", "kerfuffle"},
+		{"wildcard normalized", Rule{Pattern: "kerf*", Kind: KindWildcard}, "KÉRF!", "****!"},
+		{"wildcard leetspeak", Rule{Pattern: "*uffle", Kind: KindWildcard}, "k3rfuffl3", "****"},
+		{"wildcard possessive", Rule{Pattern: "*uffle", Kind: KindWildcard}, "kerfuffle's", "****'s"},
+
+		// Regexes.
+		{"regex", Rule{Pattern: `kerf+uffle`, Kind: KindRegex}, "kerffffuffle", "****"},
+		{"regex case insensitive", Rule{Pattern: `kerfuffle`, Kind: KindRegex}, "KerFuffle", "****"},
+		{"regex across words", Rule{Pattern: `big\s+kerfuffle`, Kind: KindRegex}, "a big   kerfuffle here", "a ****   **** here"},
+		{"regex sees punctuation", Rule{Pattern: `kerfuffle!`, Kind: KindRegex}, "kerfuffle! kerfuffle", "****! kerfuffle"},
+		{"regex word boundary", Rule{Pattern: `\bkerfuffle\b`, Kind: KindRegex}, "kerfuffle's kerfuffles", "****'s kerfuffles"},
+		{"regex accents", Rule{Pattern: `kerfuffle`, Kind: KindRegex}, "kérfuffle", "****"},
+		{"regex fullwidth", Rule{Pattern: `kerfuffle`, Kind: KindRegex}, "ｋｅｒｆｕｆｆｌｅ", "****"},
+		{"regex not leet folded", Rule{Pattern: `kerfuffle`, Kind: KindRegex}, "k3rfuffl3", "k3rfuffl3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newEngine(t, tt.rule).Apply(tt.text)
+			if got.Text != tt.want {
+				t.Errorf("Apply(%q) = %q, want %q", tt.text, got.Text, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyActions(t *testing.T) {
+	e := newEngine(t,
+		Rule{Pattern: "kerfuffle", Action: ActionReject, Reason: "rude"},
+		Rule{Pattern: "fiddle*", Kind: KindWildcard, Action: ActionFlag, Reason: "suspicious"},
+	)
+	tests := []struct {
+		text     string
+		rejected bool
+		flagged  bool
+		reasons  []string
+	}{
+		{"all fine", false, false, nil},
+		{"the kerfuffle's end", true, false, []string{"rude"}},
+		{"fiddlesticks", false, true, []string{"suspicious"}},
+		{"kerfuffle fiddlesticks", true, true, []string{"rude", "suspicious"}},
+	}
+	for _, tt := range tests {
+		got := e.Apply(tt.text)
+		if got.Text != tt.text {
+			t.Errorf("Apply(%q).Text = %q, want it unchanged", tt.text, got.Text)
+		}
+		if got.Rejected != tt.rejected || got.Flagged != tt.flagged {
+			t.Errorf("Apply(%q) rejected, flagged = %v, %v, want %v, %v", tt.text, got.Rejected, got.Flagged, tt.rejected, tt.flagged)
+		}
+		if len(got.Reasons) != len(tt.reasons) {
+			t.Errorf("Apply(%q).Reasons = %q, want %q", tt.text, got.Reasons, tt.reasons)
+			continue
+		}
+		for i := range tt.reasons {
+			if got.Reasons[i] != tt.reasons[i] {
+				t.Errorf("Apply(%q).Reasons = %q, want %q", tt.text, got.Reasons, tt.reasons)
+				break
+			}
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, rule := range []Rule{
+		{Pattern: "!!!"},
+		{Pattern: "(", Kind: KindRegex},
+		{Pattern: "kerfuffle", Kind: "glob"},
+		{Pattern: "kerfuffle", Action: "delete"},
+	} {
+		if _, err := compile(rule); err == nil {
+			t.Errorf("compile(%+v) succeeded, want an error", rule)
+		}
+	}
+}
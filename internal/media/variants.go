@@ -0,0 +1,149 @@
+package media
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"image"
+	_ "image/gif" // registers the GIF decoder
+	"image/jpeg"
+	"image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // registers the WebP decoder
+)
+
+// MaxPixels bounds the decoded size of an image, so a small file that
+// claims huge dimensions can't exhaust memory.
+const MaxPixels = 40_000_000
+
+var ErrTooLarge = errors.New("image dimensions too large")
+
+// VariantSpec describes one of the fixed renditions we generate.
+type VariantSpec struct {
+	Name string
+	// Size is the longest edge in pixels, or the side of the square when
+	// Crop is set.
+	Size int
+	Crop bool
+}
+
+// Variants are generated for every uploaded image, in addition to the
+// original.
+var Variants = []VariantSpec{
+	{Name: "thumbnail", Size: 150, Crop: true},
+	{Name: "small", Size: 480},
+}
+
+// Variant is an encoded rendition of an image.
+type Variant struct {
+	Name        string
+	Data        []byte
+	ContentType string
+	Width       int
+	Height      int
+}
+
+// Processed is what we learn from decoding an upload.
+type Processed struct {
+	Width    int
+	Height   int
+	Blurhash string
+	// Variants holds the renditions that differ from the original. A spec
+	// is left out when the original already fits within it.
+	Variants []Variant
+}
+
+// Key returns the content-addressed storage key for data: its SHA-256 and
+// the extension for contentType. Identical files share a key.
+func Key(data []byte, contentType string) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]) + Extensions[contentType]
+}
+
+// Process decodes an image, measures it, computes its blurhash and renders
+// the fixed variants. Animated GIFs are rendered from their first frame.
+func Process(data []byte) (Processed, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Processed{}, err
+	}
+	if config.Width*config.Height > MaxPixels {
+		return Processed{}, ErrTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return Processed{}, err
+	}
+
+	bounds := img.Bounds()
+	p := Processed{Width: bounds.Dx(), Height: bounds.Dy()}
+	p.Blurhash = Blurhash(scale(img, bounds, fit(bounds.Dx(), bounds.Dy(), 32)), 4, 3)
+
+	for _, spec := range Variants {
+		src := bounds
+		var dst image.Point
+		if spec.Crop {
+			side := min(bounds.Dx(), bounds.Dy())
+			x := bounds.Min.X + (bounds.Dx()-side)/2
+			y := bounds.Min.Y + (bounds.Dy()-side)/2
+			src = image.Rect(x, y, x+side, y+side)
+			size := min(side, spec.Size)
+			dst = image.Pt(size, size)
+		} else {
+			if bounds.Dx() <= spec.Size && bounds.Dy() <= spec.Size {
+				continue
+			}
+			dst = fit(bounds.Dx(), bounds.Dy(), spec.Size)
+		}
+
+		out, contentType, err := encode(scale(img, src, dst), isOpaque(img))
+		if err != nil {
+			return Processed{}, err
+		}
+		p.Variants = append(p.Variants, Variant{
+			Name:        spec.Name,
+			Data:        out,
+			ContentType: contentType,
+			Width:       dst.X,
+			Height:      dst.Y,
+		})
+	}
+	return p, nil
+}
+
+// fit returns the size of a w×h image scaled down so its longest edge is
+// at most size.
+func fit(w, h, size int) image.Point {
+	if w <= size && h <= size {
+		return image.Pt(w, h)
+	}
+	if w >= h {
+		return image.Pt(size, max(1, h*size/w))
+	}
+	return image.Pt(max(1, w*size/h), size)
+}
+
+func scale(img image.Image, src image.Rectangle, size image.Point) image.Image {
+	dst := image.NewNRGBA(image.Rect(0, 0, size.X, size.Y))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, src, draw.Src, nil)
+	return dst
+}
+
+func isOpaque(img image.Image) bool {
+	o, ok := img.(interface{ Opaque() bool })
+	return ok && o.Opaque()
+}
+
+// encode writes opaque images as JPEG and anything with transparency as
+// PNG.
+func encode(img image.Image, opaque bool) ([]byte, string, error) {
+	var buf bytes.Buffer
+	if opaque {
+		err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85})
+		return buf.Bytes(), "image/jpeg", err
+	}
+	err := png.Encode(&buf, img)
+	return buf.Bytes(), "image/png", err
+}
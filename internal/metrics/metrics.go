@@ -0,0 +1,228 @@
+// Package metrics exposes the server's Prometheus metrics: HTTP traffic,
+// database queries and pool use, and counters for things the business
+// cares about.
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "chirpy"
+
+// Metrics holds the server's collectors. The counters are exported for
+// handlers to bump directly.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests *prometheus.CounterVec
+	latency  *prometheus.HistogramVec
+	queries  *prometheus.HistogramVec
+	queryErr *prometheus.CounterVec
+
+	Signups      prometheus.Counter
+	Chirps       prometheus.Counter
+	Logins       prometheus.Counter
+	FailedLogins prometheus.Counter
+	// Webhooks counts webhooks by direction ("outgoing" or "polka") and
+	// result.
+	Webhooks *prometheus.CounterVec
+}
+
+// New registers the collectors, including pool stats for db.
+func New(db *sql.DB) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by method, route and status.",
+		}, []string{"method", "route", "status"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method, route and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		queries: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "db_query_duration_seconds",
+			Help:      "Database query latency by sqlc query name.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"query"}),
+		queryErr: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "db_query_errors_total",
+			Help:      "Database queries that returned an error, by sqlc query name.",
+		}, []string{"query"}),
+		Signups: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "signups_total",
+			Help:      "Accounts created.",
+		}),
+		Chirps: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "chirps_total",
+			Help:      "Chirps published, including scheduled ones.",
+		}),
+		Logins: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "logins_total",
+			Help:      "Successful logins.",
+		}),
+		FailedLogins: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "failed_logins_total",
+			Help:      "Logins refused for a wrong email or password.",
+		}),
+		Webhooks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "webhooks_total",
+			Help:      "Webhooks sent and received, by direction and result.",
+		}, []string{"direction", "result"}),
+	}
+	m.registry.MustRegister(
+		m.requests, m.latency, m.queries, m.queryErr,
+		m.Signups, m.Chirps, m.Logins, m.FailedLogins, m.Webhooks,
+		collectors.NewDBStatsCollector(db, namespace),
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Middleware records every request to next. The route label is the
+// ServeMux pattern that matched, so paths with IDs in them don't each get
+// their own series.
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := NewStatusWriter(w)
+		next.ServeHTTP(sw, r)
+
+		route := Route(r)
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(sw.Status)
+		m.requests.WithLabelValues(r.Method, route, status).Inc()
+		m.latency.WithLabelValues(r.Method, route, status).Observe(time.Since(start).Seconds())
+	})
+}
+
+// Route returns the path of the ServeMux pattern that matched r, without
+// its method, or "" when nothing matched.
+func Route(r *http.Request) string {
+	route := r.Pattern
+	if _, path, ok := strings.Cut(route, " "); ok {
+		route = path
+	}
+	return route
+}
+
+// StatusWriter remembers the status code written through it, for
+// middleware that reports on responses.
+type StatusWriter struct {
+	http.ResponseWriter
+	Status      int
+	wroteHeader bool
+}
+
+func NewStatusWriter(w http.ResponseWriter) *StatusWriter {
+	return &StatusWriter{ResponseWriter: w, Status: http.StatusOK}
+}
+
+func (w *StatusWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.Status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *StatusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *StatusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// DBTX is the interface sqlc's Queries runs against.
+type DBTX interface {
+	ExecContext(context.Context, string, ...interface{}) (sql.Result, error)
+	PrepareContext(context.Context, string) (*sql.Stmt, error)
+	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+	QueryRowContext(context.Context, string, ...interface{}) *sql.Row
+}
+
+// DB wraps db so each query's duration is recorded under the name from its
+// sqlc "-- name:" comment. Wrap transactions too, or their queries go
+// unrecorded.
+func (m *Metrics) DB(db DBTX) DBTX {
+	return instrumentedDB{db: db, m: m}
+}
+
+type instrumentedDB struct {
+	db DBTX
+	m  *Metrics
+}
+
+func (d instrumentedDB) observe(query string, start time.Time, err error) {
+	name := QueryName(query)
+	d.m.queries.WithLabelValues(name).Observe(time.Since(start).Seconds())
+	if err != nil && err != sql.ErrNoRows {
+		d.m.queryErr.WithLabelValues(name).Inc()
+	}
+}
+
+func (d instrumentedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	res, err := d.db.ExecContext(ctx, query, args...)
+	d.observe(query, start, err)
+	return res, err
+}
+
+func (d instrumentedDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return d.db.PrepareContext(ctx, query)
+}
+
+func (d instrumentedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := d.db.QueryContext(ctx, query, args...)
+	d.observe(query, start, err)
+	return rows, err
+}
+
+func (d instrumentedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	start := time.Now()
+	row := d.db.QueryRowContext(ctx, query, args...)
+	d.observe(query, start, row.Err())
+	return row
+}
+
+// QueryName pulls the query name out of sqlc's leading
+// "-- name: GetChirp :one" comment.
+func QueryName(query string) string {
+	rest, ok := strings.CutPrefix(query, "-- name: ")
+	if !ok {
+		return "unnamed"
+	}
+	name, _, _ := strings.Cut(rest, " ")
+	return name
+}
@@ -0,0 +1,148 @@
+package safehttp
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+// loopback allows httptest servers, which always listen on 127.0.0.1.
+var loopback = []netip.Prefix{netip.MustParsePrefix("127.0.0.1/32")}
+
+func TestAllowed(t *testing.T) {
+	tests := []struct {
+		addr  string
+		allow []netip.Prefix
+		want  bool
+	}{
+		{"8.8.8.8", nil, true},
+		{"2606:4700:4700::1111", nil, true},
+		{"127.0.0.1", nil, false},
+		{"::1", nil, false},
+		{"::ffff:127.0.0.1", nil, false},
+		{"10.1.2.3", nil, false},
+		{"172.16.0.1", nil, false},
+		{"192.168.1.1", nil, false},
+		{"169.254.169.254", nil, false},
+		{"100.64.0.1", nil, false},
+		{"0.0.0.0", nil, false},
+		{"fe80::1", nil, false},
+		{"fc00::1", nil, false},
+		{"224.0.0.1", nil, false},
+		{"198.18.0.1", nil, false},
+		{"127.0.0.1", loopback, true},
+		{"::ffff:127.0.0.1", loopback, true},
+		{"127.0.0.2", loopback, false},
+	}
+	for _, tt := range tests {
+		if got := Allowed(netip.MustParseAddr(tt.addr), tt.allow); got != tt.want {
+			t.Errorf("Allowed(%s, %v) = %v, want %v", tt.addr, tt.allow, got, tt.want)
+		}
+	}
+}
+
+func TestParseAllowList(t *testing.T) {
+	got, err := ParseAllowList(" 127.0.0.1, 10.1.0.0/16 ,, ::1 ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"127.0.0.1/32", "10.1.0.0/16", "::1/128"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i].String() != want[i] {
+			t.Errorf("entry %d = %s, want %s", i, got[i], want[i])
+		}
+	}
+
+	if _, err := ParseAllowList("localhost"); err == nil {
+		t.Error("expected an error for a hostname")
+	}
+}
+
+func TestClientRefusesPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request reached the server")
+	}))
+	defer srv.Close()
+
+	client := NewClient(Options{Timeout: time.Second, MaxRedirects: 5})
+	_, err := client.Get(srv.URL)
+	if !errors.Is(err, ErrBlockedAddress) {
+		t.Errorf("err = %v, want ErrBlockedAddress", err)
+	}
+}
+
+func TestClientAllowList(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(204)
+	}))
+	defer srv.Close()
+
+	client := NewClient(Options{Timeout: time.Second, MaxRedirects: 5, Allow: loopback})
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 204 {
+		t.Errorf("status = %d, want 204", resp.StatusCode)
+	}
+}
+
+func TestClientChecksRedirectTargets(t *testing.T) {
+	// The second server sits on another loopback address that isn't in
+	// the allowlist, standing in for an internal service.
+	ln, err := net.Listen("tcp", "127.0.0.2:0")
+	if err != nil {
+		t.Skipf("can't listen on 127.0.0.2: %v", err)
+	}
+	internal := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("redirect reached a blocked address")
+	}))
+	internal.Listener.Close()
+	internal.Listener = ln
+	internal.Start()
+	defer internal.Close()
+
+	public := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, internal.URL, http.StatusFound)
+	}))
+	defer public.Close()
+
+	client := NewClient(Options{Timeout: time.Second, MaxRedirects: 5, Allow: loopback})
+	_, err = client.Get(public.URL)
+	if !errors.Is(err, ErrBlockedAddress) {
+		t.Errorf("err = %v, want ErrBlockedAddress", err)
+	}
+}
+
+func TestClientRedirectLimits(t *testing.T) {
+	hops := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/loop":
+			hops++
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		case "/ftp":
+			http.Redirect(w, r, "ftp://example.com/file", http.StatusFound)
+		}
+	}))
+	defer srv.Close()
+
+	client := NewClient(Options{Timeout: time.Second, MaxRedirects: 3, Allow: loopback})
+	if _, err := client.Get(srv.URL + "/loop"); err == nil {
+		t.Error("expected an error after too many redirects")
+	}
+	if hops != 4 {
+		t.Errorf("followed %d requests, want 4", hops)
+	}
+	if _, err := client.Get(srv.URL + "/ftp"); err == nil {
+		t.Error("expected an error redirecting to ftp")
+	}
+}
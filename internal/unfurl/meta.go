@@ -0,0 +1,108 @@
+package unfurl
+
+import (
+	"io"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	maxTitleLen       = 200
+	maxDescriptionLen = 500
+)
+
+// parseMeta reads OpenGraph and Twitter card tags from the head of a page,
+// falling back to <title> and the description meta tag. OpenGraph wins
+// when both are present.
+func parseMeta(r io.Reader, base *url.URL) Preview {
+	meta := map[string]string{}
+	var title string
+	z := html.NewTokenizer(r)
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			if tt == html.EndTagToken {
+				name, _ := z.TagName()
+				if string(name) == "head" {
+					break
+				}
+			}
+			continue
+		}
+		name, hasAttr := z.TagName()
+		switch string(name) {
+		case "body":
+			return buildPreview(meta, title, base)
+		case "title":
+			if z.Next() == html.TextToken && title == "" {
+				title = string(z.Text())
+			}
+		case "meta":
+			var key, content string
+			for hasAttr {
+				var k, v []byte
+				k, v, hasAttr = z.TagAttr()
+				switch string(k) {
+				case "property", "name":
+					key = strings.ToLower(string(v))
+				case "content":
+					content = string(v)
+				}
+			}
+			if key != "" && content != "" {
+				if _, ok := meta[key]; !ok {
+					meta[key] = content
+				}
+			}
+		}
+	}
+	return buildPreview(meta, title, base)
+}
+
+func buildPreview(meta map[string]string, title string, base *url.URL) Preview {
+	first := func(keys ...string) string {
+		for _, k := range keys {
+			if v := strings.TrimSpace(meta[k]); v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+	p := Preview{
+		Title:       first("og:title", "twitter:title"),
+		Description: first("og:description", "twitter:description", "description"),
+		SiteName:    first("og:site_name"),
+	}
+	if p.Title == "" {
+		p.Title = strings.TrimSpace(title)
+	}
+	p.Title = truncate(collapseSpace(p.Title), maxTitleLen)
+	p.Description = truncate(collapseSpace(p.Description), maxDescriptionLen)
+	p.SiteName = truncate(collapseSpace(p.SiteName), maxTitleLen)
+
+	// Only keep absolute http(s) image links, resolved against the page.
+	if image := first("og:image", "og:image:url", "twitter:image", "twitter:image:src"); image != "" {
+		if u, err := base.Parse(image); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+			p.ImageURL = u.String()
+		}
+	}
+	return p
+}
+
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:n-1]) + "…"
+}
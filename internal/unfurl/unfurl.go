@@ -0,0 +1,112 @@
+package unfurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/tristenkelly/chirpy/internal/safehttp"
+)
+
+const (
+	DefaultTimeout      = 5 * time.Second
+	DefaultMaxBytes     = 512 << 10
+	DefaultMaxRedirects = 5
+)
+
+var ErrNotHTML = errors.New("not an HTML page")
+
+// Preview is the metadata we show for a link.
+type Preview struct {
+	URL         string
+	Title       string
+	Description string
+	ImageURL    string
+	SiteName    string
+}
+
+// Fetcher retrieves the preview for a URL.
+type Fetcher interface {
+	Fetch(ctx context.Context, rawURL string) (Preview, error)
+}
+
+// Client fetches pages over HTTP with a hard timeout, a cap on how much of
+// the body is read, and SSRF protection from safehttp: connections to
+// non-public addresses are refused unless the address falls in Allow.
+type Client struct {
+	Timeout      time.Duration
+	MaxBytes     int64
+	MaxRedirects int
+	Allow        []netip.Prefix
+	UserAgent    string
+}
+
+func New(allow []netip.Prefix) *Client {
+	return &Client{
+		Timeout:      DefaultTimeout,
+		MaxBytes:     DefaultMaxBytes,
+		MaxRedirects: DefaultMaxRedirects,
+		Allow:        allow,
+		UserAgent:    "Chirpy-LinkPreview/1.0",
+	}
+}
+
+func (c *Client) Fetch(ctx context.Context, rawURL string) (Preview, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Preview{}, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return Preview{}, fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return Preview{}, err
+	}
+	req.Header.Set("User-Agent", c.UserAgent)
+	req.Header.Set("Accept", "text/html")
+
+	client := safehttp.NewClient(safehttp.Options{
+		Timeout:      c.Timeout,
+		MaxRedirects: c.MaxRedirects,
+		Allow:        c.Allow,
+	})
+	resp, err := client.Do(req)
+	if err != nil {
+		return Preview{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Preview{}, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || (mediaType != "text/html" && mediaType != "application/xhtml+xml") {
+		return Preview{}, ErrNotHTML
+	}
+
+	// Metadata lives in the head, so a truncated page is still useful.
+	preview := parseMeta(io.LimitReader(resp.Body, c.MaxBytes), resp.Request.URL)
+	preview.URL = rawURL
+	return preview, nil
+}
+
+var urlPattern = regexp.MustCompile(`https?://[^\s]+`)
+
+// FirstURL returns the first http(s) link in text, without any trailing
+// punctuation that belongs to the sentence rather than the link.
+func FirstURL(text string) string {
+	return strings.TrimRight(urlPattern.FindString(text), `.,;:!?'")]}`)
+}
@@ -0,0 +1,260 @@
+package unfurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tristenkelly/chirpy/internal/safehttp"
+)
+
+// loopback lets the client reach httptest servers, which listen on
+// 127.0.0.1 and would otherwise be refused.
+var loopback = []netip.Prefix{netip.MustParsePrefix("127.0.0.1/32")}
+
+func TestParseMeta(t *testing.T) {
+	base, _ := url.Parse("https://example.com/posts/1")
+	tests := []struct {
+		name string
+		html string
+		want Preview
+	}{
+		{
+			name: "opengraph wins",
+			html: `<html><head>
+				<title>Page title</title>
+				<meta name="twitter:title" content="Twitter title">
+				<meta property="og:title" content="OG title">
+				<meta name="description" content="Plain description">
+				<meta property="og:description" content="OG  description
+					over lines">
+				<meta property="og:site_name" content="Example">
+				<meta property="og:image" content="/img/card.png">
+			</head><body></body></html>`,
+			want: Preview{
+				Title:       "OG title",
+				Description: "OG description over lines",
+				SiteName:    "Example",
+				ImageURL:    "https://example.com/img/card.png",
+			},
+		},
+		{
+			name: "falls back to twitter, title and description",
+			html: `<head><title> Just a title </title>
+				<meta name="description" content="Described">
+				<meta name="twitter:image" content="https://cdn.example.com/a.jpg">
+			</head>`,
+			want: Preview{
+				Title:       "Just a title",
+				Description: "Described",
+				ImageURL:    "https://cdn.example.com/a.jpg",
+			},
+		},
+		{
+			name: "drops non-http images",
+			html: `<head><meta property="og:image" content="javascript:alert(1)"></head>`,
+			want: Preview{},
+		},
+		{
+			name: "ignores tags in the body",
+			html: `<head><meta property="og:title" content="Head"></head>
+				<body><meta property="og:description" content="Body"></body>`,
+			want: Preview{Title: "Head"},
+		},
+		{
+			name: "first tag of a kind wins",
+			html: `<head><meta property="og:title" content="First">
+				<meta property="og:title" content="Second"></head>`,
+			want: Preview{Title: "First"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseMeta(strings.NewReader(tt.html), base)
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMetaTruncates(t *testing.T) {
+	base, _ := url.Parse("https://example.com/")
+	page := fmt.Sprintf(`<head><meta property="og:title" content="%s">
+		<meta property="og:description" content="%s"></head>`,
+		strings.Repeat("t", 1000), strings.Repeat("d", 1000))
+	got := parseMeta(strings.NewReader(page), base)
+	if n := utf8.RuneCountInString(got.Title); n != maxTitleLen {
+		t.Errorf("title is %d runes, want %d", n, maxTitleLen)
+	}
+	if n := utf8.RuneCountInString(got.Description); n != maxDescriptionLen {
+		t.Errorf("description is %d runes, want %d", n, maxDescriptionLen)
+	}
+	if !strings.HasSuffix(got.Title, "…") {
+		t.Errorf("title %q isn't marked as cut", got.Title)
+	}
+}
+
+func newTestClient() *Client {
+	c := New(loopback)
+	c.Timeout = time.Second
+	return c
+}
+
+func TestFetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ua := r.Header.Get("User-Agent"); !strings.HasPrefix(ua, "Chirpy-LinkPreview") {
+			t.Errorf("User-Agent = %q", ua)
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, `<head><meta property="og:title" content="Hello"><meta property="og:image" content="/a.png"></head>`)
+	}))
+	defer srv.Close()
+
+	got, err := newTestClient().Fetch(context.Background(), srv.URL+"/page")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Preview{URL: srv.URL + "/page", Title: "Hello", ImageURL: srv.URL + "/a.png"}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestFetchRejects(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/json":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{}`)
+		case "/missing":
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	c := newTestClient()
+
+	if _, err := c.Fetch(context.Background(), srv.URL+"/json"); !errors.Is(err, ErrNotHTML) {
+		t.Errorf("JSON page: err = %v, want ErrNotHTML", err)
+	}
+	if _, err := c.Fetch(context.Background(), srv.URL+"/missing"); err == nil {
+		t.Error("404 page: expected an error")
+	}
+	if _, err := c.Fetch(context.Background(), "file:///etc/passwd"); err == nil {
+		t.Error("file URL: expected an error")
+	}
+}
+
+func TestFetchRefusesPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request reached a private address")
+	}))
+	defer srv.Close()
+
+	// No allowlist, as in production.
+	c := New(nil)
+	c.Timeout = time.Second
+	_, err := c.Fetch(context.Background(), srv.URL)
+	if !errors.Is(err, safehttp.ErrBlockedAddress) {
+		t.Errorf("err = %v, want ErrBlockedAddress", err)
+	}
+}
+
+func TestFetchSizeCap(t *testing.T) {
+	padding := strings.Repeat("<!-- padding -->", 1000)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, `<head><meta property="og:title" content="Early">%s<meta property="og:description" content="Late"></head>`, padding)
+	}))
+	defer srv.Close()
+
+	c := newTestClient()
+	c.MaxBytes = 1024
+	got, err := c.Fetch(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Title != "Early" {
+		t.Errorf("title = %q, want the tag before the cap", got.Title)
+	}
+	if got.Description != "" {
+		t.Errorf("description = %q, want nothing from past the cap", got.Description)
+	}
+}
+
+func TestFetchTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	c := newTestClient()
+	c.Timeout = 100 * time.Millisecond
+	start := time.Now()
+	_, err := c.Fetch(context.Background(), srv.URL)
+	if err == nil {
+		t.Fatal("expected a timeout")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Fetch took %v, want it cut off near the timeout", elapsed)
+	}
+}
+
+func TestFetchRedirects(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/short":
+			http.Redirect(w, r, "/articles/long", http.StatusMovedPermanently)
+		case "/articles/long":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<head><title>Moved</title><meta property="og:image" content="img.png"></head>`)
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		}
+	}))
+	defer srv.Close()
+	c := newTestClient()
+
+	got, err := c.Fetch(context.Background(), srv.URL+"/short")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The preview keeps the link as posted, but relative images resolve
+	// against the page that was actually served.
+	want := Preview{URL: srv.URL + "/short", Title: "Moved", ImageURL: srv.URL + "/articles/img.png"}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	if _, err := c.Fetch(context.Background(), srv.URL+"/loop"); err == nil {
+		t.Error("redirect loop: expected an error")
+	}
+}
+
+func TestFirstURL(t *testing.T) {
+	tests := map[string]string{
+		"no links here":                           "",
+		"see https://example.com/a.":              "https://example.com/a",
+		"(http://example.com/x?y=1) and more":     "http://example.com/x?y=1",
+		"two https://a.example https://b.example": "https://a.example",
+		"ftp://example.com isn't previewed":       "",
+	}
+	for text, want := range tests {
+		if got := FirstURL(text); got != want {
+			t.Errorf("FirstURL(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
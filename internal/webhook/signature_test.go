@@ -0,0 +1,111 @@
+package webhook
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testSecret = "whsec_test"
+	oldSecret  = "whsec_old"
+)
+
+var testBody = []byte(`{"id":"evt_1"}`)
+
+// sentAt is when the test requests are signed; now is set relative to it.
+var sentAt = time.Unix(1700000000, 0)
+
+func TestSignKnownAnswer(t *testing.T) {
+	// HMAC-SHA256 of `1700000000.{"id":"evt_1"}` under "whsec_test",
+	// computed independently of this package.
+	want := "t=1700000000,v1=c89214b5b5da833daed6f0b8c5bb6bd58cea9022bd80ccc78230f3942d632925"
+	if got := Sign(testSecret, sentAt, testBody); got != want {
+		t.Errorf("Sign = %q, want %q", got, want)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	valid := Sign(testSecret, sentAt, testBody)
+	_, newSig, _ := strings.Cut(valid, ",")
+	_, oldSig, _ := strings.Cut(Sign(oldSecret, sentAt, testBody), ",")
+
+	tests := []struct {
+		name   string
+		header string
+		secret string
+		body   []byte
+		now    time.Time
+		want   error
+	}{
+		{"valid", valid, testSecret, testBody, sentAt, nil},
+		{"tampered body", valid, testSecret, []byte(`{"id":"evt_2"}`), sentAt, ErrInvalidSignature},
+		{"wrong secret", valid, "whsec_other", testBody, sentAt, ErrInvalidSignature},
+		{"just inside tolerance, old", valid, testSecret, testBody, sentAt.Add(DefaultTolerance), nil},
+		{"just outside tolerance, old", valid, testSecret, testBody, sentAt.Add(DefaultTolerance + time.Second), ErrExpired},
+		{"just inside tolerance, ahead", valid, testSecret, testBody, sentAt.Add(-DefaultTolerance), nil},
+		{"just outside tolerance, ahead", valid, testSecret, testBody, sentAt.Add(-DefaultTolerance - time.Second), ErrExpired},
+		{"signed with old and new secret", "t=1700000000," + oldSig + "," + newSig, testSecret, testBody, sentAt, nil},
+		{"signed with old secret only", "t=1700000000," + oldSig, testSecret, testBody, sentAt, ErrInvalidSignature},
+		{"spaces around parts", "t=1700000000, " + newSig, testSecret, testBody, sentAt, nil},
+		{"missing header", "", testSecret, testBody, sentAt, ErrMissingSignature},
+		{"garbage", "not a signature", testSecret, testBody, sentAt, ErrInvalidSignature},
+		{"no timestamp", newSig, testSecret, testBody, sentAt, ErrInvalidSignature},
+		{"no signature", "t=1700000000", testSecret, testBody, sentAt, ErrInvalidSignature},
+		{"timestamp not a number", "t=soon," + newSig, testSecret, testBody, sentAt, ErrInvalidSignature},
+		{"signature not hex", "t=1700000000,v1=zz", testSecret, testBody, sentAt, ErrInvalidSignature},
+		{"timestamp changed", "t=1700000001," + newSig, testSecret, testBody, sentAt, ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.header, tt.secret, tt.body, tt.now, DefaultTolerance)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Verify(%q) = %v, want %v", tt.header, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyHeaders(t *testing.T) {
+	ts := strconv.FormatInt(sentAt.Unix(), 10)
+	_, v1, _ := strings.Cut(Sign(testSecret, sentAt, testBody), ",v1=")
+	_, oldV1, _ := strings.Cut(Sign(oldSecret, sentAt, testBody), ",v1=")
+
+	tests := []struct {
+		name      string
+		timestamp string
+		signature string
+		secrets   []string
+		body      []byte
+		now       time.Time
+		want      error
+	}{
+		{"valid", ts, v1, []string{testSecret}, testBody, sentAt, nil},
+		{"sha256= prefix", ts, "sha256=" + v1, []string{testSecret}, testBody, sentAt, nil},
+		{"second secret during rotation", ts, v1, []string{oldSecret, testSecret}, testBody, sentAt, nil},
+		{"first secret during rotation", ts, oldV1, []string{oldSecret, testSecret}, testBody, sentAt, nil},
+		{"retired secret", ts, oldV1, []string{testSecret}, testBody, sentAt, ErrInvalidSignature},
+		{"tampered body", ts, v1, []string{testSecret}, []byte(`{"id":"evt_2"}`), sentAt, ErrInvalidSignature},
+		{"wrong secret", ts, v1, []string{"whsec_other"}, testBody, sentAt, ErrInvalidSignature},
+		{"no secrets", ts, v1, nil, testBody, sentAt, ErrInvalidSignature},
+		{"just inside tolerance, old", ts, v1, []string{testSecret}, testBody, sentAt.Add(DefaultTolerance), nil},
+		{"just outside tolerance, old", ts, v1, []string{testSecret}, testBody, sentAt.Add(DefaultTolerance + time.Second), ErrExpired},
+		{"just inside tolerance, ahead", ts, v1, []string{testSecret}, testBody, sentAt.Add(-DefaultTolerance), nil},
+		{"just outside tolerance, ahead", ts, v1, []string{testSecret}, testBody, sentAt.Add(-DefaultTolerance - time.Second), ErrExpired},
+		{"missing timestamp", "", v1, []string{testSecret}, testBody, sentAt, ErrMissingSignature},
+		{"missing signature", ts, "", []string{testSecret}, testBody, sentAt, ErrMissingSignature},
+		{"signature not hex", ts, "not-hex", []string{testSecret}, testBody, sentAt, ErrInvalidSignature},
+		{"timestamp not a number", "yesterday", v1, []string{testSecret}, testBody, sentAt, ErrInvalidSignature},
+		{"timestamp changed", "1700000001", v1, []string{testSecret}, testBody, sentAt, ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyHeaders(tt.timestamp, tt.signature, tt.secrets, tt.body, tt.now, DefaultTolerance)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("VerifyHeaders = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/tristenkelly/chirpy/internal/database"
+	"github.com/tristenkelly/chirpy/internal/unfurl"
+)
+
+// A cached preview is fetched again once it is older than its TTL.
+// Failures are retried much sooner than successes are refreshed. The age
+// is checked by the database, which holds fetched_at as local wall-clock
+// time.
+const (
+	linkPreviewTTL       = 7 * 24 * time.Hour
+	linkPreviewRetryTTL  = time.Hour
+	linkPreviewQueueSize = 64
+)
+
+type linkPreviewResponse struct {
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	ImageURL    string `json:"image_url,omitempty"`
+	SiteName    string `json:"site_name,omitempty"`
+}
+
+// runLinkPreviewer fetches link previews in the background, one at a
+// time, so a slow site never holds up a request.
+func (cfg *apiConfig) runLinkPreviewer(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case url := <-cfg.linkJobs:
+			cfg.fetchLinkPreview(ctx, url)
+		}
+	}
+}
+
+// queueLinkPreview asks the previewer to fetch a link. If the queue is
+// full the link is fetched the next time a chirp with it is shown.
+func (cfg *apiConfig) queueLinkPreview(url string) {
+	if url == "" {
+		return
+	}
+	select {
+	case cfg.linkJobs <- url:
+	default:
+	}
+}
+
+func (cfg *apiConfig) fetchLinkPreview(ctx context.Context, url string) {
+	now := time.Now()
+	cached, err := cfg.db.GetLinkPreview(ctx, database.GetLinkPreviewParams{
+		RefreshBefore: now.Add(-linkPreviewTTL),
+		RetryBefore:   now.Add(-linkPreviewRetryTTL),
+		Url:           url,
+	})
+	if err == nil && !cached.Stale {
+		return
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("error getting link preview for %s: %v", url, err)
+		return
+	}
+
+	// A failed fetch is cached too, so a dead or blocked link isn't
+	// retried every time it's shown.
+	preview, err := cfg.unfurler.Fetch(ctx, url)
+	if err != nil {
+		log.Printf("error fetching link preview for %s: %v", url, err)
+	}
+	ok := err == nil && preview.Title != ""
+	err = cfg.db.UpsertLinkPreview(ctx, database.UpsertLinkPreviewParams{
+		Url:         url,
+		FetchedAt:   now,
+		Ok:          ok,
+		Title:       preview.Title,
+		Description: preview.Description,
+		ImageUrl:    preview.ImageURL,
+		SiteName:    preview.SiteName,
+	})
+	if err != nil {
+		log.Printf("error saving link preview for %s: %v", url, err)
+	}
+}
+
+// linkPreviews loads the cached previews for the first link in each chirp,
+// keyed by URL. Links with no preview yet, or a stale one, are queued
+// for fetching.
+func (cfg *apiConfig) linkPreviews(ctx context.Context, chirps []database.Chirp) (map[string]*linkPreviewResponse, error) {
+	resp := map[string]*linkPreviewResponse{}
+	var urls []string
+	for _, chirp := range chirps {
+		if url := unfurl.FirstURL(chirp.Body); url != "" {
+			urls = append(urls, url)
+		}
+	}
+	if len(urls) == 0 {
+		return resp, nil
+	}
+
+	now := time.Now()
+	cached, err := cfg.db.GetLinkPreviews(ctx, database.GetLinkPreviewsParams{
+		RefreshBefore: now.Add(-linkPreviewTTL),
+		RetryBefore:   now.Add(-linkPreviewRetryTTL),
+		Urls:          urls,
+	})
+	if err != nil {
+		return nil, err
+	}
+	found := map[string]bool{}
+	for _, p := range cached {
+		found[p.Url] = true
+		if p.Stale {
+			cfg.queueLinkPreview(p.Url)
+		}
+		if !p.Ok {
+			continue
+		}
+		resp[p.Url] = &linkPreviewResponse{
+			URL:         p.Url,
+			Title:       p.Title,
+			Description: p.Description,
+			ImageURL:    p.ImageUrl,
+			SiteName:    p.SiteName,
+		}
+	}
+	for _, url := range urls {
+		if !found[url] {
+			cfg.queueLinkPreview(url)
+		}
+	}
+	return resp, nil
+}
@@ -1,45 +1,78 @@
 package main
 
 import (
+	"context"
+	"crypto/subtle"
 	"database/sql"
 	"encoding/json"
-	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"sort"
+	"strconv"
 	"strings"
-	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
+	"github.com/tristenkelly/chirpy/internal/activitypub"
 	"github.com/tristenkelly/chirpy/internal/auth"
+	"github.com/tristenkelly/chirpy/internal/chirplen"
 	"github.com/tristenkelly/chirpy/internal/database"
+	"github.com/tristenkelly/chirpy/internal/entitlements"
+	"github.com/tristenkelly/chirpy/internal/filter"
+	"github.com/tristenkelly/chirpy/internal/metrics"
+	"github.com/tristenkelly/chirpy/internal/safehttp"
+	"github.com/tristenkelly/chirpy/internal/storage"
+	"github.com/tristenkelly/chirpy/internal/tracing"
+	"github.com/tristenkelly/chirpy/internal/unfurl"
 )
 
 type apiConfig struct {
-	fileserverHits atomic.Int32
-	db             *database.Queries
-	platform       string
-	secret         string
-	polka          string
+	db            *database.Queries
+	sqlDB         *sql.DB
+	platform      string
+	secret        string
+	polka         []string
+	filter        *filter.Engine
+	filterFile    string
+	tiers         entitlements.Config
+	urlWeight     int
+	media         storage.Store
+	maxMediaBytes int64
+	mediaJobs     chan uuid.UUID
+	trends        trendCache
+	analytics     analyticsBuffer
+	unfurler      unfurl.Fetcher
+	linkJobs      chan string
+	publicURL     string
+	apClient      *activitypub.Client
+	webhookClient *http.Client
+	metrics       *metrics.Metrics
+	metricsToken  string
 }
 
+// chirpResponse is a chirp as the API returns it. Collapsed tells clients to
+// hide a sensitive chirp, or one with a content warning, until it's opened,
+// following the viewer's preference.
 type chirpResponse struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
-	UserID    uuid.UUID `json:"user_id"`
-}
-
-func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		cfg.fileserverHits.Add(1)
-		next.ServeHTTP(w, r)
-	})
+	ID             uuid.UUID            `json:"id"`
+	CreatedAt      time.Time            `json:"created_at"`
+	UpdatedAt      time.Time            `json:"updated_at"`
+	Body           string               `json:"body"`
+	UserID         uuid.UUID            `json:"user_id"`
+	Media          []mediaResponse      `json:"media"`
+	Poll           *pollResponse        `json:"poll,omitempty"`
+	Visibility     string               `json:"visibility"`
+	Bookmarked     bool                 `json:"bookmarked"`
+	Pinned         bool                 `json:"pinned"`
+	ContentWarning string               `json:"content_warning"`
+	Sensitive      bool                 `json:"sensitive"`
+	Collapsed      bool                 `json:"collapsed"`
+	LinkPreview    *linkPreviewResponse `json:"link_preview,omitempty"`
 }
 
 func health(w http.ResponseWriter, r *http.Request) {
@@ -48,10 +81,28 @@ func health(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("OK"))
 }
 
-func (cfg *apiConfig) metrics(w http.ResponseWriter, r *http.Request) {
-	w.Header().Add("Content-Type", "text/html; charset=utf-8")
-	msg := fmt.Sprintf("<html><body><h1>Welcome, Chirpy Admin</h1><p>Chirpy has been visited %d times!</p></body></html>", cfg.fileserverHits.Load())
-	w.Write([]byte(msg))
+// serveMetrics serves Prometheus metrics. When METRICS_TOKEN is set the
+// scraper must send it as a bearer token.
+func (cfg *apiConfig) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	if cfg.metricsToken != "" {
+		token, err := auth.GetBearerToken(r.Header)
+		if err != nil || subtle.ConstantTimeCompare([]byte(token), []byte(cfg.metricsToken)) != 1 {
+			w.WriteHeader(401)
+			return
+		}
+	}
+	cfg.metrics.Handler().ServeHTTP(w, r)
+}
+
+// fromTimestamp returns the instant held in a TIMESTAMP column. Times are
+// stored as local wall-clock time, but lib/pq reads them back labelled UTC.
+func fromTimestamp(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.Local)
+}
+
+// withTx returns queries that run in tx and are still timed and traced.
+func (cfg *apiConfig) withTx(tx *sql.Tx) *database.Queries {
+	return database.New(tracing.DB(cfg.metrics.DB(tx)))
 }
 
 func (cfg *apiConfig) reset(w http.ResponseWriter, r *http.Request) {
@@ -90,7 +141,9 @@ func (cfg *apiConfig) createUser(w http.ResponseWriter, r *http.Request) {
 		IsChirpyRed bool      `json:"is_chirpy_red"`
 	}
 	currentTime := time.Now()
+	_, span := tracing.Start(r.Context(), "auth.HashPassword")
 	hashedPassword, err := auth.HashPassword(params.Password)
+	span.End()
 	if err != nil {
 		log.Printf("error hashing password %v", err)
 		w.WriteHeader(500)
@@ -125,6 +178,7 @@ func (cfg *apiConfig) createUser(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(500)
 		return
 	}
+	cfg.metrics.Signups.Inc()
 	w.WriteHeader(201)
 	w.Write(val)
 
@@ -151,15 +205,24 @@ func (cfg *apiConfig) getChirps(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
-	var apiChirp []chirpResponse
+	viewer, loggedIn := cfg.viewerID(r)
+	filter, err := cfg.loadChirpFilter(r.Context(), viewer, loggedIn)
+	if err != nil {
+		log.Printf("error loading chirp filter: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	var visible []database.Chirp
 	for _, chirp := range data {
-		apiChirp = append(apiChirp, chirpResponse{
-			ID:        chirp.ID,
-			Body:      chirp.Body,
-			CreatedAt: chirp.CreatedAt,
-			UpdatedAt: chirp.UpdatedAt,
-			UserID:    chirp.UserID,
-		})
+		if filter.allows(chirp) {
+			visible = append(visible, chirp)
+		}
+	}
+	apiChirp, err := cfg.chirpResponses(r.Context(), viewer, visible)
+	if err != nil {
+		log.Printf("error building chirp responses: %v", err)
+		w.WriteHeader(500)
+		return
 	}
 	if sortType == "desc" {
 		sort.Slice(apiChirp, func(arg1 int, arg2 int) bool {
@@ -170,6 +233,20 @@ func (cfg *apiConfig) getChirps(w http.ResponseWriter, r *http.Request) {
 			}
 		})
 	}
+	if s != "" && r.URL.Query().Get("include_pinned") == "true" {
+		authorID, _ := uuid.Parse(s)
+		apiChirp, err = cfg.withPinned(r.Context(), viewer, filter, authorID, apiChirp)
+		if err != nil {
+			log.Printf("error getting pinned chirps: %v", err)
+			w.WriteHeader(500)
+			return
+		}
+	}
+	cfg.recordImpressions(r, viewer, loggedIn, apiChirp)
+	if s != "" {
+		authorID, _ := uuid.Parse(s)
+		cfg.recordProfileVisit(r, viewer, loggedIn, authorID)
+	}
 	val, err := json.Marshal(apiChirp)
 	if err != nil {
 		log.Printf("error marshaling chirp data %v", err)
@@ -190,14 +267,37 @@ func (cfg *apiConfig) getChirp(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(404)
 		return
 	}
-	validChirp := chirpResponse{
-		ID:        data.ID,
-		CreatedAt: data.CreatedAt,
-		UpdatedAt: data.UpdatedAt,
-		Body:      data.Body,
-		UserID:    data.UserID,
+	viewer, loggedIn := cfg.viewerID(r)
+	filter, err := cfg.loadChirpFilter(r.Context(), viewer, loggedIn)
+	if err != nil {
+		log.Printf("error loading chirp filter: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	if !filter.canSee(data) {
+		w.WriteHeader(404)
+		return
+	}
+	if loggedIn {
+		blocked, err := cfg.isBlocked(r.Context(), viewer, data.UserID)
+		if err != nil {
+			log.Printf("error checking block: %v", err)
+			w.WriteHeader(500)
+			return
+		}
+		if blocked {
+			w.WriteHeader(404)
+			return
+		}
 	}
-	val, err := json.Marshal(validChirp)
+	resp, err := cfg.chirpResponses(r.Context(), viewer, []database.Chirp{data})
+	if err != nil {
+		log.Printf("error building chirp response: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	cfg.recordImpressions(r, viewer, loggedIn, resp)
+	val, err := json.Marshal(resp[0])
 	if err != nil {
 		log.Printf("error marshaling chirp data %v", err)
 	}
@@ -209,8 +309,13 @@ func (cfg *apiConfig) validChirp(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	type paramaters struct {
-		Body   string    `json:"body"`
-		UserID uuid.UUID `json:"user_id"`
+		Body           string      `json:"body"`
+		UserID         uuid.UUID   `json:"user_id"`
+		MediaIDs       []uuid.UUID `json:"media_ids"`
+		Poll           *pollParams `json:"poll"`
+		Visibility     string      `json:"visibility"`
+		ContentWarning string      `json:"content_warning"`
+		Sensitive      bool        `json:"sensitive"`
 	}
 
 	decoder := json.NewDecoder(r.Body)
@@ -231,7 +336,9 @@ func (cfg *apiConfig) validChirp(w http.ResponseWriter, r *http.Request) {
 	}
 
 	type errorResponse struct {
-		Error string `json:"error"`
+		Error  string `json:"error"`
+		Length int    `json:"length,omitempty"`
+		Limit  int    `json:"limit,omitempty"`
 	}
 
 	respBodyValid := returnValsValid{
@@ -242,16 +349,6 @@ func (cfg *apiConfig) validChirp(w http.ResponseWriter, r *http.Request) {
 		Error: "",
 	}
 
-	cleanBody := cleanedBody{
-		Cleaned_Body: filterText(params.Body),
-	}
-
-	if len(params.Body) <= 140 {
-		respBodyValid.Valid = true
-	} else if len(params.Body) > 140 {
-		respError.Error = "Chirp is too long"
-	}
-
 	token, err := auth.GetBearerToken(r.Header)
 	if err != nil {
 		log.Printf("error getting bearer token %v", err)
@@ -266,26 +363,137 @@ func (cfg *apiConfig) validChirp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	author, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		log.Printf("error getting chirp author: %v", err)
+		w.WriteHeader(401)
+		return
+	}
+
+	if author.SuspendedAt.Valid {
+		w.WriteHeader(403)
+		return
+	}
+
+	filtered := cfg.filter.Apply(params.Body)
+	cleanBody := cleanedBody{
+		Cleaned_Body: filtered.Text,
+	}
+
+	limits := cfg.limitsFor(author)
+	length := chirplen.Count(params.Body, cfg.urlWeight)
+	limit := limits.MaxChirpLength
+	if filtered.Rejected {
+		respError.Error = "Chirp rejected: " + strings.Join(filtered.Reasons, ", ")
+	} else if length <= limit {
+		respBodyValid.Valid = true
+	} else {
+		respError.Error = "Chirp is too long"
+		respError.Length = length
+		respError.Limit = limit
+	}
+
+	if respBodyValid.Valid && len(params.MediaIDs) > limits.MaxMedia {
+		respBodyValid.Valid = false
+		respError.Error = "Too many attachments"
+	}
+	for _, mediaID := range params.MediaIDs {
+		if !respBodyValid.Valid {
+			break
+		}
+		m, err := cfg.db.GetMedia(r.Context(), mediaID)
+		if err != nil || m.UserID != userID || m.ChirpID.Valid {
+			respBodyValid.Valid = false
+			respError.Error = "Invalid attachment " + mediaID.String()
+		}
+	}
+	if params.Visibility == "" {
+		params.Visibility = visibilityPublic
+	}
+	if respBodyValid.Valid && !visibilities[params.Visibility] {
+		respBodyValid.Valid = false
+		respError.Error = "Invalid visibility " + params.Visibility
+	}
+	cwFiltered, msg := cfg.checkContentWarning(params.ContentWarning)
+	if respBodyValid.Valid && msg != "" {
+		respBodyValid.Valid = false
+		respError.Error = msg
+	}
+	if respBodyValid.Valid && params.Poll != nil {
+		if msg := cfg.validatePoll(params.Poll, time.Now()); msg != "" {
+			respBodyValid.Valid = false
+			respError.Error = msg
+		}
+	}
+
 	if respBodyValid.Valid {
 		chirpParams := database.CreateChirpParams{
-			ID:        uuid.New(),
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
-			Body:      cleanBody.Cleaned_Body,
-			UserID:    userID,
+			ID:             uuid.New(),
+			CreatedAt:      time.Now(),
+			UpdatedAt:      time.Now(),
+			Body:           cleanBody.Cleaned_Body,
+			UserID:         userID,
+			Visibility:     params.Visibility,
+			ContentWarning: cwFiltered.Text,
+			Sensitive:      params.Sensitive,
+		}
+		// The chirp, its attachments and its poll are created together, so
+		// a failure part way doesn't leave a half-built chirp.
+		tx, err := cfg.sqlDB.BeginTx(r.Context(), nil)
+		if err != nil {
+			log.Printf("error starting transaction: %v", err)
+			w.WriteHeader(500)
+			return
 		}
-		chirp, err := cfg.db.CreateChirp(r.Context(), chirpParams)
+		defer tx.Rollback()
+		q := cfg.withTx(tx)
+
+		chirp, err := q.CreateChirp(r.Context(), chirpParams)
 		if err != nil {
 			log.Printf("error creating chirp %v", err)
+			w.WriteHeader(500)
+			return
 		}
-		validChirpResponse := chirpResponse{
-			ID:        chirp.ID,
-			CreatedAt: chirp.CreatedAt,
-			UpdatedAt: chirp.UpdatedAt,
-			Body:      chirp.Body,
-			UserID:    chirp.UserID,
+		for i, mediaID := range params.MediaIDs {
+			err := q.AttachMedia(r.Context(), database.AttachMediaParams{
+				ID:       mediaID,
+				ChirpID:  uuid.NullUUID{UUID: chirp.ID, Valid: true},
+				Position: int32(i),
+			})
+			if err != nil {
+				log.Printf("error attaching media %v: %v", mediaID, err)
+				w.WriteHeader(500)
+				return
+			}
 		}
-		val, err := json.Marshal(validChirpResponse)
+		if params.Poll != nil {
+			err := createPoll(r.Context(), q, chirp.ID, *params.Poll)
+			if err != nil {
+				log.Printf("error creating poll: %v", err)
+				w.WriteHeader(500)
+				return
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			log.Printf("error committing chirp: %v", err)
+			w.WriteHeader(500)
+			return
+		}
+
+		if filtered.Flagged || cwFiltered.Flagged {
+			cfg.flagForReview(r.Context(), chirp, append(filtered.Reasons, cwFiltered.Reasons...))
+		}
+		cfg.queueLinkPreview(unfurl.FirstURL(chirp.Body))
+		cfg.metrics.Chirps.Inc()
+		cfg.federateChirp(r.Context(), chirp)
+		cfg.emitChirpCreated(r.Context(), chirp)
+		validChirpResponse, err := cfg.chirpResponses(r.Context(), userID, []database.Chirp{chirp})
+		if err != nil {
+			log.Printf("error building chirp response: %v", err)
+			w.WriteHeader(500)
+			return
+		}
+		val, err := json.Marshal(validChirpResponse[0])
 		if err != nil {
 			log.Printf("Error marshalling JSON: %s", err)
 			w.WriteHeader(500)
@@ -370,9 +578,12 @@ func (cfg *apiConfig) handleLogin(w http.ResponseWriter, r *http.Request) {
 		Refresh_token: refreshToken,
 	}
 
+	_, span := tracing.Start(r.Context(), "auth.CheckPasswordHash")
 	err2 := auth.CheckPasswordHash(params.Password, user.HashedPassword)
+	span.End()
 	if err2 != nil {
 		log.Println("incorrect password")
+		cfg.metrics.FailedLogins.Inc()
 		w.WriteHeader(401)
 		return
 	}
@@ -390,6 +601,7 @@ func (cfg *apiConfig) handleLogin(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(500)
 		return
 	}
+	cfg.metrics.Logins.Inc()
 	w.WriteHeader(200)
 	w.Write(val)
 }
@@ -497,7 +709,9 @@ func (cfg *apiConfig) changePassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	_, span := tracing.Start(r.Context(), "auth.HashPassword")
 	newPass, err := auth.HashPassword(params.Password)
+	span.End()
 	if err != nil {
 		log.Printf("error hashing password: %v", err)
 		w.WriteHeader(500)
@@ -586,12 +800,16 @@ func (cfg *apiConfig) deleteChirp(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if chirp.UserID == userID {
+		cfg.deleteChirpMedia(r.Context(), chirp.ID)
 		err3 := cfg.db.DeleteChirp(r.Context(), rqParams)
 		if err != nil {
 			log.Printf("error deleting chirp %v", err3)
 			w.WriteHeader(500)
 			return
 		}
+		cfg.recordDeletedChirp(r.Context(), chirp.ID)
+		cfg.federateDelete(r.Context(), chirp)
+		cfg.emitChirpDeleted(r.Context(), chirp)
 		w.WriteHeader(204)
 		w.Write([]byte("chirp deleted"))
 	} else {
@@ -600,75 +818,22 @@ func (cfg *apiConfig) deleteChirp(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (cfg *apiConfig) upgradeUser(w http.ResponseWriter, r *http.Request) {
-	token, err := auth.GetAPIToken(r.Header)
-	if err != nil {
-		log.Printf("error getting token: %v", err)
-		w.WriteHeader(401)
-		return
+// envInt reads an integer setting, falling back to def when it is unset.
+func envInt(name string, def int) int {
+	s := os.Getenv(name)
+	if s == "" {
+		return def
 	}
-
-	if token != cfg.polka {
-		w.WriteHeader(401)
-		return
-	}
-
-	type paramaters struct {
-		Event string `json:"event"`
-		Data  struct {
-			Userid string `json:"user_id"`
-		} `json:"data"`
-	}
-	decoder := json.NewDecoder(r.Body)
-	params := paramaters{}
-	err2 := decoder.Decode(&params)
-	if err2 != nil {
-		log.Printf("error decoding json: %V", err)
-		w.WriteHeader(500)
-		return
-	}
-
-	if params.Event != "user.upgraded" {
-		w.WriteHeader(204)
-		return
-	}
-	userID, err := uuid.Parse(params.Data.Userid)
+	n, err := strconv.Atoi(s)
 	if err != nil {
-		log.Printf("error parsing string into uuid: %v", err)
-		w.WriteHeader(500)
-		return
-	}
-	rqParams := database.UpgradeUserParams{
-		ID:          userID,
-		IsChirpyRed: true,
-		UpdatedAt:   time.Now(),
-	}
-
-	err3 := cfg.db.UpgradeUser(r.Context(), rqParams)
-	if err3 != nil {
-		log.Printf("error getting user in table: %v", err)
-		w.WriteHeader(404)
-		return
-	} else {
-		w.WriteHeader(204)
+		log.Fatalf("invalid %s: %v", name, err)
 	}
+	return n
 }
 
-func filterText(text string) string {
-	sliceStr := strings.Split(text, " ")
-	badWords := map[string]bool{
-		"kerfuffle": true,
-		"sharbert":  true,
-		"fornax":    true,
-	}
-	for i, str := range sliceStr {
-		if badWords[strings.ToLower(str)] {
-			sliceStr[i] = "****"
-		}
-	}
-	newString := strings.Join(sliceStr, " ")
-	return newString
-}
+// shutdownTimeout bounds how long main waits for in-flight requests and
+// the final analytics and trace flushes after a signal.
+const shutdownTimeout = 30 * time.Second
 
 func main() {
 	envErr := godotenv.Load()
@@ -677,44 +842,269 @@ func main() {
 	}
 	dbURL := os.Getenv("DB_URL")
 	platform := os.Getenv("PLATFORM")
-	polka := os.Getenv("POLKA_KEY")
+	// POLKA_KEY may list several comma-separated secrets while one is
+	// being rotated out.
+	var polka []string
+	for _, key := range strings.Split(os.Getenv("POLKA_KEY"), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			polka = append(polka, key)
+		}
+	}
 	secret := os.Getenv("SECRET")
 	db, err2 := sql.Open("postgres", dbURL)
 	if err2 != nil {
 		log.Fatal("error making SQL connection")
 	}
-	dbQueries := database.New(db)
+	// ctx is cancelled on SIGINT or SIGTERM, which stops the background
+	// workers and starts the shutdown below.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	// TRACING_EXPORTER is "otlp" (configured by the standard
+	// OTEL_EXPORTER_OTLP_* variables), "stdout", or "file" to append spans
+	// to TRACING_FILE. Tracing is off when it is unset.
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Options{
+		Exporter:    os.Getenv("TRACING_EXPORTER"),
+		File:        os.Getenv("TRACING_FILE"),
+		ServiceName: "chirpy",
+	})
+	if err != nil {
+		log.Fatalf("error setting up tracing: %v", err)
+	}
+	appMetrics := metrics.New(db)
+	dbQueries := database.New(tracing.DB(appMetrics.DB(db)))
 	mux := http.NewServeMux()
 
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+	// Tracing goes outermost: it replaces the request to carry the span,
+	// and the metrics middleware reads the pattern the mux sets on the
+	// request it was given.
 	server := &http.Server{
-		Addr:    ":8080",
-		Handler: mux,
+		Addr:    ":" + port,
+		Handler: tracing.Middleware(appMetrics.Middleware(mux)),
 	}
 
-	apiCfg := &apiConfig{
-		db:       dbQueries,
-		platform: platform,
-		secret:   secret,
-		polka:    polka,
+	filterFile := os.Getenv("FILTER_RULES_FILE")
+	filterSource := dbFilterSource(dbQueries)
+	if filterFile != "" {
+		filterSource = filter.FileSource(filterFile)
+	}
+	contentFilter := filter.New(filterSource)
+	if err := contentFilter.Reload(context.Background()); err != nil {
+		log.Fatal("error loading filter rules: ", err)
+	}
+
+	// What each account tier can do comes from ENTITLEMENTS_FILE. Without
+	// one the defaults apply, with CHIRP_LIMIT_FREE and CHIRP_LIMIT_RED
+	// still setting the chirp lengths.
+	tiers := entitlements.Default()
+	if path := os.Getenv("ENTITLEMENTS_FILE"); path != "" {
+		tiers, err = entitlements.Load(path)
+		if err != nil {
+			log.Fatal("error loading entitlements: ", err)
+		}
+	} else {
+		free, red := tiers[entitlements.Free], tiers[entitlements.Red]
+		free.MaxChirpLength = envInt("CHIRP_LIMIT_FREE", free.MaxChirpLength)
+		red.MaxChirpLength = envInt("CHIRP_LIMIT_RED", red.MaxChirpLength)
+		tiers[entitlements.Free], tiers[entitlements.Red] = free, red
+	}
+
+	mediaStore, err := newMediaStore()
+	if err != nil {
+		log.Fatal("error setting up media storage: ", err)
+	}
+
+	// Link previews never reach private addresses unless they're listed
+	// here, e.g. LINK_PREVIEW_ALLOW=10.0.5.0/24 for an internal wiki.
+	previewAllow, err := safehttp.ParseAllowList(os.Getenv("LINK_PREVIEW_ALLOW"))
+	if err != nil {
+		log.Fatal("error parsing LINK_PREVIEW_ALLOW: ", err)
 	}
 
-	mux.Handle("/app/", apiCfg.middlewareMetricsInc(http.StripPrefix("/app/", http.FileServer(http.Dir(".")))))
+	// Federation is off unless PUBLIC_URL says where this instance lives.
+	// FEDERATION_ALLOW works like LINK_PREVIEW_ALLOW; two instances on one
+	// machine need FEDERATION_ALLOW=127.0.0.1/32 to talk to each other.
+	publicURL := strings.TrimSuffix(os.Getenv("PUBLIC_URL"), "/")
+	federationAllow, err := safehttp.ParseAllowList(os.Getenv("FEDERATION_ALLOW"))
+	if err != nil {
+		log.Fatal("error parsing FEDERATION_ALLOW: ", err)
+	}
+	apClient := &activitypub.Client{
+		HTTP: safehttp.NewClient(safehttp.Options{
+			Timeout:      10 * time.Second,
+			MaxRedirects: 3,
+			Allow:        federationAllow,
+		}),
+		UserAgent: "Chirpy/1.0 (+" + publicURL + ")",
+	}
+
+	// Webhook endpoints are user-supplied URLs, so they get the same
+	// protection; WEBHOOK_ALLOW opens up private ranges for testing.
+	webhookAllow, err := safehttp.ParseAllowList(os.Getenv("WEBHOOK_ALLOW"))
+	if err != nil {
+		log.Fatal("error parsing WEBHOOK_ALLOW: ", err)
+	}
+
+	apiCfg := &apiConfig{
+		db:            dbQueries,
+		sqlDB:         db,
+		metrics:       appMetrics,
+		metricsToken:  os.Getenv("METRICS_TOKEN"),
+		platform:      platform,
+		secret:        secret,
+		polka:         polka,
+		filter:        contentFilter,
+		filterFile:    filterFile,
+		tiers:         tiers,
+		urlWeight:     envInt("CHIRP_URL_WEIGHT", chirplen.DefaultURLWeight),
+		media:         mediaStore,
+		maxMediaBytes: int64(envInt("MEDIA_MAX_BYTES", 5<<20)),
+		mediaJobs:     make(chan uuid.UUID, 64),
+		unfurler:      unfurl.New(previewAllow),
+		linkJobs:      make(chan string, linkPreviewQueueSize),
+		publicURL:     publicURL,
+		apClient:      apClient,
+		webhookClient: safehttp.NewClient(safehttp.Options{
+			Timeout: 10 * time.Second,
+			Allow:   webhookAllow,
+		}),
+	}
+	go apiCfg.runMediaWorker(ctx)
+	go apiCfg.runPollCloser(ctx)
+	go apiCfg.runScheduler(ctx)
+	go apiCfg.runTrends(ctx)
+	go apiCfg.runLinkPreviewer(ctx)
+	go apiCfg.runDeliveryWorker(ctx)
+	go apiCfg.runWebhookWorker(ctx)
+	go apiCfg.runSubscriptionSweeper(ctx)
+	flusherDone := make(chan struct{})
+	go func() {
+		apiCfg.runAnalyticsFlusher(ctx)
+		close(flusherDone)
+	}()
+
+	// Only static/ is served, never the working directory, which also
+	// holds .env and uploaded media.
+	mux.Handle("/app/", http.StripPrefix("/app/", http.FileServer(http.Dir("static"))))
+	mux.HandleFunc("GET /media/{key}", apiCfg.serveMedia)
 	mux.HandleFunc("GET /api/healthz", health)
-	mux.HandleFunc("GET /admin/metrics", apiCfg.metrics)
+	mux.HandleFunc("GET /metrics", apiCfg.serveMetrics)
 	mux.HandleFunc("POST /admin/reset", apiCfg.reset)
 	mux.HandleFunc("POST /api/users", apiCfg.createUser)
 	mux.HandleFunc("POST /api/chirps", apiCfg.validChirp)
 	mux.HandleFunc("GET /api/chirps", apiCfg.getChirps)
+	mux.HandleFunc("GET /api/trends", apiCfg.getTrends)
 	mux.HandleFunc("GET /api/chirps/{chirpID}", apiCfg.getChirp)
 	mux.HandleFunc("POST /api/login", apiCfg.handleLogin)
 	mux.HandleFunc("POST /api/refresh", apiCfg.getRefreshToken)
 	mux.HandleFunc("POST /api/revoke", apiCfg.revokeRefreshToken)
 	mux.HandleFunc("PUT /api/users", apiCfg.changePassword)
+	mux.HandleFunc("GET /api/users/preferences", apiCfg.getPreferences)
+	mux.HandleFunc("GET /api/users/me/subscription", apiCfg.getSubscription)
+	mux.HandleFunc("GET /api/users/me/entitlements", apiCfg.getEntitlements)
+	mux.HandleFunc("GET /api/users/me/analytics", apiCfg.getUserAnalytics)
+	mux.HandleFunc("PUT /api/users/preferences", apiCfg.updatePreferences)
 	mux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.deleteChirp)
-	mux.HandleFunc("POST /api/polka/webhooks", apiCfg.upgradeUser)
-
-	err := http.ListenAndServe(server.Addr, server.Handler)
-	if err != nil {
+	mux.HandleFunc("POST /api/polka/webhooks", apiCfg.polkaWebhook)
+	mux.HandleFunc("POST /api/conversations", apiCfg.createConversation)
+	mux.HandleFunc("GET /api/conversations", apiCfg.getConversations)
+	mux.HandleFunc("GET /api/conversations/{id}/messages", apiCfg.getMessages)
+	mux.HandleFunc("POST /api/conversations/{id}/messages", apiCfg.createMessage)
+	mux.HandleFunc("PUT /api/users/{userID}/block", apiCfg.blockUser)
+	mux.HandleFunc("DELETE /api/users/{userID}/block", apiCfg.unblockUser)
+	mux.HandleFunc("PUT /api/users/{userID}/mute", apiCfg.muteUser)
+	mux.HandleFunc("DELETE /api/users/{userID}/mute", apiCfg.unmuteUser)
+	mux.HandleFunc("GET /api/blocks", apiCfg.getBlocks)
+	mux.HandleFunc("PUT /api/users/{userID}/follow", apiCfg.followUser)
+	mux.HandleFunc("DELETE /api/users/{userID}/follow", apiCfg.unfollowUser)
+	mux.HandleFunc("GET /api/follows", apiCfg.getFollows)
+	mux.HandleFunc("POST /api/muted_words", apiCfg.createMutedWord)
+	mux.HandleFunc("GET /api/muted_words", apiCfg.getMutedWords)
+	mux.HandleFunc("DELETE /api/muted_words/{wordID}", apiCfg.deleteMutedWord)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/report", apiCfg.reportChirp)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/poll/votes", apiCfg.votePoll)
+	mux.HandleFunc("PUT /api/chirps/{chirpID}/bookmark", apiCfg.bookmarkChirp)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/bookmark", apiCfg.unbookmarkChirp)
+	mux.HandleFunc("PUT /api/chirps/{chirpID}/pin", apiCfg.pinChirp)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/pin", apiCfg.unpinChirp)
+	mux.HandleFunc("GET /api/chirps/{chirpID}/analytics", apiCfg.getChirpAnalytics)
+	mux.HandleFunc("GET /api/bookmarks", apiCfg.getBookmarks)
+	mux.HandleFunc("POST /api/lists", apiCfg.createList)
+	mux.HandleFunc("GET /api/lists", apiCfg.getLists)
+	mux.HandleFunc("GET /api/lists/{listID}", apiCfg.getList)
+	mux.HandleFunc("PUT /api/lists/{listID}", apiCfg.updateList)
+	mux.HandleFunc("DELETE /api/lists/{listID}", apiCfg.deleteList)
+	mux.HandleFunc("PUT /api/lists/{listID}/members/{userID}", apiCfg.addListMember)
+	mux.HandleFunc("DELETE /api/lists/{listID}/members/{userID}", apiCfg.removeListMember)
+	mux.HandleFunc("GET /api/lists/{listID}/chirps", apiCfg.getListChirps)
+	mux.HandleFunc("POST /api/drafts", apiCfg.createDraft)
+	mux.HandleFunc("GET /api/drafts", apiCfg.getDrafts)
+	mux.HandleFunc("GET /api/drafts/{draftID}", apiCfg.getDraft)
+	mux.HandleFunc("PUT /api/drafts/{draftID}", apiCfg.updateDraft)
+	mux.HandleFunc("DELETE /api/drafts/{draftID}", apiCfg.deleteDraft)
+	mux.HandleFunc("GET /api/notifications", apiCfg.getNotifications)
+	mux.HandleFunc("POST /api/notifications/read", apiCfg.markNotificationsRead)
+	mux.HandleFunc("GET /api/moderation/reports", apiCfg.getReports)
+	mux.HandleFunc("POST /api/moderation/reports/{reportID}/dismiss", apiCfg.dismissReport)
+	mux.HandleFunc("POST /api/moderation/chirps/{chirpID}/hide", apiCfg.hideChirp)
+	mux.HandleFunc("POST /api/moderation/chirps/{chirpID}/unhide", apiCfg.unhideChirp)
+	mux.HandleFunc("POST /api/moderation/chirps/{chirpID}/sensitive", apiCfg.markChirpSensitive)
+	mux.HandleFunc("DELETE /api/moderation/chirps/{chirpID}", apiCfg.removeChirp)
+	mux.HandleFunc("POST /api/moderation/users/{userID}/suspend", apiCfg.suspendUser)
+	mux.HandleFunc("POST /api/moderation/users/{userID}/unsuspend", apiCfg.unsuspendUser)
+	mux.HandleFunc("GET /api/moderation/actions", apiCfg.getModerationActions)
+	mux.HandleFunc("GET /api/moderation/filter/rules", apiCfg.getFilterRules)
+	mux.HandleFunc("POST /api/moderation/filter/rules", apiCfg.createFilterRule)
+	mux.HandleFunc("DELETE /api/moderation/filter/rules/{ruleID}", apiCfg.deleteFilterRule)
+	mux.HandleFunc("POST /api/moderation/filter/reload", apiCfg.reloadFilterRules)
+	mux.HandleFunc("POST /api/media", apiCfg.uploadMedia)
+	mux.HandleFunc("POST /api/federation/follows", apiCfg.createRemoteFollow)
+	mux.HandleFunc("GET /api/federation/follows", apiCfg.getRemoteFollows)
+	mux.HandleFunc("DELETE /api/federation/follows/{followID}", apiCfg.deleteRemoteFollow)
+	mux.HandleFunc("POST /api/webhooks", apiCfg.createWebhook)
+	mux.HandleFunc("GET /api/webhooks", apiCfg.getWebhooks)
+	mux.HandleFunc("PUT /api/webhooks/{webhookID}", apiCfg.updateWebhook)
+	mux.HandleFunc("DELETE /api/webhooks/{webhookID}", apiCfg.deleteWebhook)
+	mux.HandleFunc("GET /api/webhooks/{webhookID}/deliveries", apiCfg.getWebhookDeliveries)
+	mux.HandleFunc("POST /api/webhooks/{webhookID}/deliveries/{deliveryID}/redeliver", apiCfg.redeliverWebhook)
+	mux.HandleFunc("GET /c/{chirpID}", apiCfg.getChirpPage)
+	mux.HandleFunc("GET /oembed", apiCfg.oembed)
+	mux.HandleFunc("GET /users/{userID}/{feed}", apiCfg.getUserFeed)
+	mux.HandleFunc("GET /tags/{tag}/{feed}", apiCfg.getTagFeed)
+	mux.HandleFunc("GET /.well-known/webfinger", apiCfg.webfinger)
+	mux.HandleFunc("GET /ap/users/{userID}", apiCfg.getActor)
+	mux.HandleFunc("GET /ap/users/{userID}/outbox", apiCfg.getOutbox)
+	mux.HandleFunc("GET /ap/users/{userID}/followers", apiCfg.getFollowersCollection)
+	mux.HandleFunc("POST /ap/users/{userID}/inbox", apiCfg.inbox)
+	mux.HandleFunc("GET /ap/chirps/{chirpID}", apiCfg.getNote)
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+	select {
+	case err := <-serverErr:
 		log.Fatal("error starting server", err)
+	case <-ctx.Done():
+	}
+	stop()
+
+	// Let in-flight requests finish, then write out the analytics they
+	// buffered before flushing the spans recorded along the way.
+	log.Println("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("error shutting down server: %v", err)
+	}
+	// A periodic flush cut off by the signal puts its batch back, so wait
+	// for it before taking what's left.
+	<-flusherDone
+	apiCfg.flushAnalytics(shutdownCtx)
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		log.Printf("error shutting down tracing: %v", err)
 	}
 }
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tristenkelly/chirpy/internal/database"
+	"github.com/tristenkelly/chirpy/internal/media"
+)
+
+// runMediaWorker generates variants for uploaded images. Uploads nudge it
+// through cfg.mediaJobs; a periodic sweep picks up anything that was
+// missed, such as uploads from before a restart.
+func (cfg *apiConfig) runMediaWorker(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	cfg.processPendingMedia(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-cfg.mediaJobs:
+			m, err := cfg.db.GetMedia(ctx, id)
+			if err != nil {
+				log.Printf("error getting media %v: %v", id, err)
+				continue
+			}
+			if !m.ProcessedAt.Valid {
+				cfg.processMedia(ctx, m)
+			}
+		case <-ticker.C:
+			cfg.processPendingMedia(ctx)
+		}
+	}
+}
+
+// queueMedia asks the worker to process an upload. If the queue is full
+// the sweep will get to it instead.
+func (cfg *apiConfig) queueMedia(id uuid.UUID) {
+	select {
+	case cfg.mediaJobs <- id:
+	default:
+	}
+}
+
+func (cfg *apiConfig) processPendingMedia(ctx context.Context) {
+	pending, err := cfg.db.GetUnprocessedMedia(ctx, 20)
+	if err != nil {
+		log.Printf("error getting unprocessed media: %v", err)
+		return
+	}
+	for _, m := range pending {
+		cfg.processMedia(ctx, m)
+	}
+}
+
+func (cfg *apiConfig) processMedia(ctx context.Context, m database.MediaFile) {
+	rc, err := cfg.media.Get(ctx, m.StorageKey)
+	if err != nil {
+		log.Printf("error reading media %v: %v", m.ID, err)
+		return
+	}
+	data, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		log.Printf("error reading media %v: %v", m.ID, err)
+		return
+	}
+
+	processed, err := media.Process(data)
+	if err != nil {
+		// The file will never decode, so mark it done rather than retrying
+		// it on every sweep. It is still served as uploaded.
+		log.Printf("error processing media %v: %v", m.ID, err)
+		err = cfg.db.SetMediaProcessed(ctx, database.SetMediaProcessedParams{
+			ID:          m.ID,
+			ProcessedAt: sql.NullTime{Time: time.Now(), Valid: true},
+		})
+		if err != nil {
+			log.Printf("error updating media %v: %v", m.ID, err)
+		}
+		return
+	}
+
+	original := database.UpsertMediaVariantParams{
+		MediaID:     m.ID,
+		Name:        "original",
+		StorageKey:  m.StorageKey,
+		ContentType: m.ContentType,
+		Width:       int32(processed.Width),
+		Height:      int32(processed.Height),
+		SizeBytes:   m.SizeBytes,
+	}
+	for _, v := range processed.Variants {
+		variant := database.UpsertMediaVariantParams{
+			MediaID:     m.ID,
+			Name:        v.Name,
+			StorageKey:  media.Key(v.Data, v.ContentType),
+			ContentType: v.ContentType,
+			Width:       int32(v.Width),
+			Height:      int32(v.Height),
+			SizeBytes:   int64(len(v.Data)),
+		}
+		err := cfg.storeMediaKey(ctx, variant.StorageKey, v.Data, v.ContentType, func(q *database.Queries) error {
+			return q.UpsertMediaVariant(ctx, variant)
+		})
+		if err != nil {
+			log.Printf("error saving %s variant of media %v: %v", v.Name, m.ID, err)
+			// The media may have been deleted while we worked on it.
+			cfg.releaseMediaKey(ctx, variant.StorageKey)
+			return
+		}
+	}
+	// Images that already fit a variant's size use the original for it.
+	// Its file is kept by the media row, so there is nothing to store.
+	reused := []database.UpsertMediaVariantParams{original}
+	for _, spec := range media.Variants {
+		found := false
+		for _, v := range processed.Variants {
+			found = found || v.Name == spec.Name
+		}
+		if !found {
+			v := original
+			v.Name = spec.Name
+			reused = append(reused, v)
+		}
+	}
+	for _, v := range reused {
+		err := cfg.db.UpsertMediaVariant(ctx, v)
+		if err != nil {
+			log.Printf("error saving %s variant of media %v: %v", v.Name, m.ID, err)
+			return
+		}
+	}
+
+	err = cfg.db.SetMediaProcessed(ctx, database.SetMediaProcessedParams{
+		ID:          m.ID,
+		Width:       sql.NullInt32{Int32: int32(processed.Width), Valid: true},
+		Height:      sql.NullInt32{Int32: int32(processed.Height), Valid: true},
+		Blurhash:    sql.NullString{String: processed.Blurhash, Valid: true},
+		ProcessedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		log.Printf("error updating media %v: %v", m.ID, err)
+	}
+}
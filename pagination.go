@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 100
+)
+
+// encodeCursor packs the sort key of the last row on a page into an opaque
+// string the client hands back to fetch the next page.
+func encodeCursor(createdAt time.Time, id uuid.UUID) string {
+	raw := fmt.Sprintf("%d|%s", createdAt.UnixNano(), id.String())
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(cursor string) (time.Time, uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, uuid.UUID{}, err
+	}
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 {
+		return time.Time{}, uuid.UUID{}, fmt.Errorf("malformed cursor")
+	}
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return time.Time{}, uuid.UUID{}, err
+	}
+	id, err := uuid.Parse(parts[1])
+	if err != nil {
+		return time.Time{}, uuid.UUID{}, err
+	}
+	return time.Unix(0, nanos).UTC(), id, nil
+}
+
+// pageParams reads the cursor and limit query parameters. With no cursor the
+// page starts at the newest row.
+func pageParams(r *http.Request) (time.Time, uuid.UUID, int32, error) {
+	limit := defaultPageSize
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return time.Time{}, uuid.UUID{}, 0, fmt.Errorf("invalid limit")
+		}
+		limit = min(n, maxPageSize)
+	}
+	cursor := r.URL.Query().Get("cursor")
+	if cursor == "" {
+		return time.Now().Add(time.Hour), uuid.Max, int32(limit), nil
+	}
+	before, beforeID, err := decodeCursor(cursor)
+	if err != nil {
+		return time.Time{}, uuid.UUID{}, 0, err
+	}
+	return before, beforeID, int32(limit), nil
+}
@@ -0,0 +1,57 @@
+-- name: RecordAnalyticsEvents :exec
+WITH seen AS (
+    INSERT INTO analytics_seen(kind, subject_id, viewer, day)
+    SELECT e.kind, e.subject_id, e.viewer, e.day::date
+    FROM unnest(
+        sqlc.arg(kinds)::text[],
+        sqlc.arg(subject_ids)::uuid[],
+        sqlc.arg(viewers)::text[],
+        sqlc.arg(days)::text[]
+    ) AS e(kind, subject_id, viewer, day)
+    ON CONFLICT DO NOTHING
+    RETURNING kind, subject_id, day
+), chirp_counts AS (
+    INSERT INTO chirp_stats_daily(chirp_id, day, impressions, engagements)
+    SELECT seen.subject_id, seen.day,
+    COUNT(*) FILTER (WHERE seen.kind = 'impression'),
+    COUNT(*) FILTER (WHERE seen.kind = 'engagement')
+    FROM seen
+    JOIN chirps ON chirps.id = seen.subject_id
+    WHERE seen.kind IN ('impression', 'engagement')
+    GROUP BY seen.subject_id, seen.day
+    ON CONFLICT (chirp_id, day) DO UPDATE
+    SET impressions = chirp_stats_daily.impressions + EXCLUDED.impressions,
+    engagements = chirp_stats_daily.engagements + EXCLUDED.engagements
+)
+INSERT INTO user_stats_daily(user_id, day, profile_visits)
+SELECT seen.subject_id, seen.day, COUNT(*)
+FROM seen
+JOIN users ON users.id = seen.subject_id
+WHERE seen.kind = 'profile_visit'
+GROUP BY seen.subject_id, seen.day
+ON CONFLICT (user_id, day) DO UPDATE
+SET profile_visits = user_stats_daily.profile_visits + EXCLUDED.profile_visits;
+
+-- name: DeleteOldAnalyticsSeen :exec
+DELETE FROM analytics_seen
+WHERE day < $1::date;
+
+-- name: GetChirpStats :many
+SELECT day, impressions, engagements FROM chirp_stats_daily
+WHERE chirp_id = $1 AND day >= $2::date
+ORDER BY day ASC;
+
+-- name: GetAuthorStats :many
+SELECT chirp_stats_daily.day,
+SUM(chirp_stats_daily.impressions)::bigint AS impressions,
+SUM(chirp_stats_daily.engagements)::bigint AS engagements
+FROM chirp_stats_daily
+JOIN chirps ON chirps.id = chirp_stats_daily.chirp_id
+WHERE chirps.user_id = $1 AND chirp_stats_daily.day >= $2::date
+GROUP BY chirp_stats_daily.day
+ORDER BY chirp_stats_daily.day ASC;
+
+-- name: GetProfileVisits :many
+SELECT day, profile_visits FROM user_stats_daily
+WHERE user_id = $1 AND day >= $2::date
+ORDER BY day ASC;
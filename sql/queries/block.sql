@@ -0,0 +1,74 @@
+-- name: BlockUser :exec
+INSERT INTO user_blocks(blocker_id, blocked_id, created_at)
+VALUES (
+    $1,
+    $2,
+    $3
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UnblockUser :exec
+DELETE FROM user_blocks
+WHERE blocker_id = $1 AND blocked_id = $2;
+
+-- name: GetBlockedUsers :many
+SELECT * FROM user_blocks
+WHERE blocker_id = $1
+ORDER BY created_at DESC;
+
+-- name: GetBlockRelations :many
+SELECT blocked_id AS user_id FROM user_blocks
+WHERE blocker_id = $1
+UNION
+SELECT blocker_id AS user_id FROM user_blocks
+WHERE blocked_id = $1;
+
+-- name: IsBlockedEitherWay :one
+SELECT EXISTS(
+    SELECT 1 FROM user_blocks
+    WHERE (blocker_id = $1 AND blocked_id = $2)
+    OR (blocker_id = $2 AND blocked_id = $1)
+);
+
+-- name: MuteUser :exec
+INSERT INTO user_mutes(muter_id, muted_id, created_at)
+VALUES (
+    $1,
+    $2,
+    $3
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UnmuteUser :exec
+DELETE FROM user_mutes
+WHERE muter_id = $1 AND muted_id = $2;
+
+-- name: GetMutedUsers :many
+SELECT * FROM user_mutes
+WHERE muter_id = $1
+ORDER BY created_at DESC;
+
+-- name: CreateMutedWord :one
+INSERT INTO muted_words(id, created_at, user_id, word, expires_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+RETURNING *;
+
+-- name: GetMutedWords :many
+SELECT * FROM muted_words
+WHERE user_id = $1
+ORDER BY created_at DESC;
+
+-- name: GetActiveMutedWords :many
+SELECT * FROM muted_words
+WHERE user_id = $1
+AND (expires_at IS NULL OR expires_at > $2);
+
+-- name: DeleteMutedWord :exec
+DELETE FROM muted_words
+WHERE id = $1 AND user_id = $2;
@@ -1,16 +1,19 @@
 -- name: CreateChirp :one
-INSERT INTO chirps(id, created_at, updated_at, body, user_id)
+INSERT INTO chirps(id, created_at, updated_at, body, user_id, visibility, content_warning, sensitive)
 VALUES (
     $1,
     $2,
     $3,
     $4,
-    $5
+    $5,
+    $6,
+    $7,
+    $8
 )
 RETURNING *;
 
 -- name: ResetChirps :exec
-TRUNCATE chirps;
+TRUNCATE chirps CASCADE;
 
 -- name: GetAllChirps :many
 SELECT * FROM chirps
@@ -27,4 +30,32 @@ WHERE id = $1 AND user_id = $2;
 -- name: GetChirpsForUser :many
 SELECT * FROM chirps
 WHERE user_id = $1
-ORDER BY created_at ASC;
\ No newline at end of file
+ORDER BY created_at ASC;
+
+-- name: SetChirpHidden :exec
+UPDATE chirps
+SET hidden_at = $2,
+updated_at = $3
+WHERE id = $1;
+
+-- name: RemoveChirp :exec
+DELETE FROM chirps
+WHERE id = $1;
+
+-- name: SetChirpSensitive :exec
+UPDATE chirps
+SET sensitive = true,
+content_warning = $2,
+updated_at = $3
+WHERE id = $1;
+
+-- name: RecordDeletedChirp :exec
+INSERT INTO deleted_chirps(id, deleted_at)
+VALUES ($1, $2)
+ON CONFLICT (id) DO NOTHING;
+
+-- name: IsChirpDeleted :one
+SELECT EXISTS (
+    SELECT 1 FROM deleted_chirps
+    WHERE id = $1
+);
@@ -0,0 +1,75 @@
+-- name: CreateConversation :one
+INSERT INTO conversations(id, created_at, updated_at, created_by)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4
+)
+RETURNING *;
+
+-- name: AddConversationMember :exec
+INSERT INTO conversation_members(conversation_id, user_id, joined_at)
+VALUES (
+    $1,
+    $2,
+    $3
+);
+
+-- name: GetConversation :one
+SELECT * FROM conversations
+WHERE id = $1;
+
+-- name: GetConversationMembers :many
+SELECT * FROM conversation_members
+WHERE conversation_id = $1
+ORDER BY joined_at ASC;
+
+-- name: IsConversationMember :one
+SELECT EXISTS(
+    SELECT 1 FROM conversation_members
+    WHERE conversation_id = $1 AND user_id = $2
+);
+
+-- name: GetConversationsForUser :many
+SELECT c.id, c.created_at, c.updated_at, m.last_read_at,
+(
+    SELECT COUNT(*) FROM messages msg
+    WHERE msg.conversation_id = c.id
+    AND msg.sender_id <> m.user_id
+    AND (m.last_read_at IS NULL OR msg.created_at > m.last_read_at)
+) AS unread_count
+FROM conversations c
+JOIN conversation_members m ON m.conversation_id = c.id
+WHERE m.user_id = $1
+ORDER BY c.updated_at DESC;
+
+-- name: TouchConversation :exec
+UPDATE conversations
+SET updated_at = $2
+WHERE id = $1;
+
+-- name: MarkConversationRead :exec
+UPDATE conversation_members
+SET last_read_at = $3
+WHERE conversation_id = $1 AND user_id = $2
+AND (last_read_at IS NULL OR last_read_at < $3);
+
+-- name: CreateMessage :one
+INSERT INTO messages(id, created_at, updated_at, conversation_id, sender_id, body)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+)
+RETURNING *;
+
+-- name: GetMessagesPage :many
+SELECT * FROM messages
+WHERE conversation_id = sqlc.arg(conversation_id)
+AND (created_at, id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_size);
@@ -0,0 +1,56 @@
+-- name: CreateDraft :one
+INSERT INTO drafts(id, created_at, updated_at, user_id, body, publish_at, visibility, content_warning, sensitive)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9
+)
+RETURNING *;
+
+-- name: GetDraft :one
+SELECT * FROM drafts
+WHERE id = $1 AND user_id = $2;
+
+-- name: GetDrafts :many
+SELECT * FROM drafts
+WHERE user_id = $1 AND publish_at IS NULL
+ORDER BY updated_at DESC;
+
+-- name: GetScheduledChirps :many
+SELECT * FROM drafts
+WHERE user_id = $1 AND publish_at IS NOT NULL
+ORDER BY publish_at ASC;
+
+-- name: UpdateDraft :one
+UPDATE drafts
+SET body = $3,
+publish_at = $4,
+visibility = $5,
+content_warning = $6,
+sensitive = $7,
+updated_at = $8
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeleteDraft :execrows
+DELETE FROM drafts
+WHERE id = $1 AND user_id = $2;
+
+-- name: ClaimDueDrafts :many
+SELECT * FROM drafts
+WHERE publish_at <= $1
+ORDER BY publish_at ASC
+LIMIT $2
+FOR UPDATE SKIP LOCKED;
+
+-- name: UnscheduleDraft :exec
+UPDATE drafts
+SET publish_at = NULL,
+updated_at = $2
+WHERE id = $1;
@@ -0,0 +1,147 @@
+-- name: GetActorKey :one
+SELECT * FROM actor_keys
+WHERE user_id = $1;
+
+-- name: CreateActorKey :exec
+INSERT INTO actor_keys(user_id, created_at, private_key_pem, public_key_pem)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4
+)
+ON CONFLICT (user_id) DO NOTHING;
+
+-- name: GetRemoteActor :one
+SELECT * FROM remote_actors
+WHERE id = $1;
+
+-- name: UpsertRemoteActor :one
+INSERT INTO remote_actors(id, fetched_at, inbox_url, shared_inbox_url, public_key_id, public_key_pem)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+)
+ON CONFLICT (id) DO UPDATE
+SET fetched_at = EXCLUDED.fetched_at,
+inbox_url = EXCLUDED.inbox_url,
+shared_inbox_url = EXCLUDED.shared_inbox_url,
+public_key_id = EXCLUDED.public_key_id,
+public_key_pem = EXCLUDED.public_key_pem
+RETURNING *;
+
+-- name: CreateRemoteFollower :exec
+INSERT INTO remote_followers(user_id, actor_id, activity_id, created_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4
+)
+ON CONFLICT (user_id, actor_id) DO UPDATE
+SET activity_id = EXCLUDED.activity_id;
+
+-- name: DeleteRemoteFollowerByActivity :exec
+DELETE FROM remote_followers
+WHERE actor_id = $1 AND activity_id = $2;
+
+-- name: CountRemoteFollowers :one
+SELECT COUNT(*) FROM remote_followers
+WHERE user_id = $1;
+
+-- name: GetFollowerInboxes :many
+SELECT DISTINCT COALESCE(NULLIF(remote_actors.shared_inbox_url, ''), remote_actors.inbox_url)::text AS inbox
+FROM remote_followers
+JOIN remote_actors ON remote_actors.id = remote_followers.actor_id
+WHERE remote_followers.user_id = $1;
+
+-- name: CreateRemoteFollow :one
+INSERT INTO remote_follows(id, created_at, user_id, actor_id)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4
+)
+ON CONFLICT (user_id, actor_id) DO UPDATE
+SET actor_id = EXCLUDED.actor_id
+RETURNING *;
+
+-- name: GetRemoteFollows :many
+SELECT * FROM remote_follows
+WHERE user_id = $1
+ORDER BY created_at DESC;
+
+-- name: GetRemoteFollow :one
+SELECT * FROM remote_follows
+WHERE id = $1 AND user_id = $2;
+
+-- name: AcceptRemoteFollow :exec
+UPDATE remote_follows
+SET accepted_at = $3
+WHERE id = $1 AND actor_id = $2;
+
+-- name: DeleteRemoteFollow :exec
+DELETE FROM remote_follows
+WHERE id = $1 AND user_id = $2;
+
+-- name: CreateRemoteLike :execrows
+INSERT INTO remote_likes(chirp_id, actor_id, activity_id, created_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4
+)
+ON CONFLICT (chirp_id, actor_id) DO NOTHING;
+
+-- name: DeleteRemoteLikeByActivity :exec
+DELETE FROM remote_likes
+WHERE actor_id = $1 AND activity_id = $2;
+
+-- name: GetPublicChirpsForUser :many
+SELECT * FROM chirps
+WHERE user_id = $1 AND visibility = 'public' AND hidden_at IS NULL
+ORDER BY created_at DESC
+LIMIT $2;
+
+-- name: CountPublicChirpsForUser :one
+SELECT COUNT(*) FROM chirps
+WHERE user_id = $1 AND visibility = 'public' AND hidden_at IS NULL;
+
+-- name: CreateDelivery :exec
+INSERT INTO federation_deliveries(id, created_at, sender_id, inbox_url, payload, next_attempt_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+);
+
+-- name: ClaimDueDeliveries :many
+SELECT * FROM federation_deliveries
+WHERE next_attempt_at <= $1
+ORDER BY next_attempt_at ASC
+LIMIT $2
+FOR UPDATE SKIP LOCKED;
+
+-- name: LeaseDeliveries :exec
+UPDATE federation_deliveries
+SET next_attempt_at = sqlc.arg(next_attempt_at)
+WHERE id = ANY(sqlc.arg(ids)::uuid[]);
+
+-- name: DeleteDelivery :exec
+DELETE FROM federation_deliveries
+WHERE id = $1;
+
+-- name: RetryDelivery :exec
+UPDATE federation_deliveries
+SET attempts = attempts + 1,
+next_attempt_at = $2
+WHERE id = $1;
@@ -0,0 +1,19 @@
+-- name: GetFilterRules :many
+SELECT * FROM filter_rules
+ORDER BY created_at ASC;
+
+-- name: CreateFilterRule :one
+INSERT INTO filter_rules(id, created_at, pattern, kind, action, reason)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+)
+RETURNING *;
+
+-- name: DeleteFilterRule :exec
+DELETE FROM filter_rules
+WHERE id = $1;
@@ -0,0 +1,27 @@
+-- name: FollowUser :execrows
+INSERT INTO follows(follower_id, followee_id, created_at)
+VALUES (
+    $1,
+    $2,
+    $3
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UnfollowUser :exec
+DELETE FROM follows
+WHERE follower_id = $1 AND followee_id = $2;
+
+-- name: GetFollowing :many
+SELECT * FROM follows
+WHERE follower_id = $1
+ORDER BY created_at DESC;
+
+-- name: GetFollowers :many
+SELECT * FROM follows
+WHERE followee_id = $1
+ORDER BY created_at DESC;
+
+-- name: DeleteFollowsBetween :exec
+DELETE FROM follows
+WHERE (follower_id = $1 AND followee_id = $2)
+OR (follower_id = $2 AND followee_id = $1);
@@ -0,0 +1,28 @@
+-- name: UpsertLinkPreview :exec
+INSERT INTO link_previews(url, fetched_at, ok, title, description, image_url, site_name)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7
+)
+ON CONFLICT (url) DO UPDATE
+SET fetched_at = EXCLUDED.fetched_at,
+ok = EXCLUDED.ok,
+title = EXCLUDED.title,
+description = EXCLUDED.description,
+image_url = EXCLUDED.image_url,
+site_name = EXCLUDED.site_name;
+
+-- name: GetLinkPreview :one
+SELECT *, fetched_at < CASE WHEN ok THEN sqlc.arg(refresh_before)::timestamp ELSE sqlc.arg(retry_before)::timestamp END AS stale
+FROM link_previews
+WHERE url = sqlc.arg(url);
+
+-- name: GetLinkPreviews :many
+SELECT *, fetched_at < CASE WHEN ok THEN sqlc.arg(refresh_before)::timestamp ELSE sqlc.arg(retry_before)::timestamp END AS stale
+FROM link_previews
+WHERE url = ANY(sqlc.arg(urls)::text[]);
@@ -0,0 +1,66 @@
+-- name: CreateList :one
+INSERT INTO lists(id, created_at, updated_at, owner_id, name, is_private)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+)
+RETURNING *;
+
+-- name: GetList :one
+SELECT * FROM lists
+WHERE id = $1;
+
+-- name: GetListsForOwner :many
+SELECT * FROM lists
+WHERE owner_id = $1
+ORDER BY created_at ASC;
+
+-- name: CountListsForOwner :one
+SELECT COUNT(*) FROM lists
+WHERE owner_id = $1;
+
+-- name: UpdateList :one
+UPDATE lists
+SET name = $3,
+is_private = $4,
+updated_at = $5
+WHERE id = $1 AND owner_id = $2
+RETURNING *;
+
+-- name: DeleteList :execrows
+DELETE FROM lists
+WHERE id = $1 AND owner_id = $2;
+
+-- name: AddListMember :exec
+INSERT INTO list_members(list_id, user_id, created_at)
+VALUES (
+    $1,
+    $2,
+    $3
+)
+ON CONFLICT DO NOTHING;
+
+-- name: RemoveListMember :exec
+DELETE FROM list_members
+WHERE list_id = $1 AND user_id = $2;
+
+-- name: GetListMembers :many
+SELECT * FROM list_members
+WHERE list_id = $1
+ORDER BY created_at ASC;
+
+-- name: CountListMembers :one
+SELECT COUNT(*) FROM list_members
+WHERE list_id = $1;
+
+-- name: GetListChirpsPage :many
+SELECT chirps.* FROM chirps
+JOIN list_members ON list_members.user_id = chirps.user_id
+WHERE list_members.list_id = sqlc.arg(list_id)
+AND (chirps.created_at, chirps.id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg(page_size);
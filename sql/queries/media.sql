@@ -0,0 +1,77 @@
+-- name: CreateMedia :one
+INSERT INTO media_files(id, created_at, user_id, storage_key, content_type, size_bytes)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+)
+RETURNING *;
+
+-- name: GetMedia :one
+SELECT * FROM media_files
+WHERE id = $1;
+
+-- name: AttachMedia :exec
+UPDATE media_files
+SET chirp_id = $2,
+position = $3
+WHERE id = $1 AND chirp_id IS NULL;
+
+-- name: GetMediaForChirps :many
+SELECT * FROM media_files
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+ORDER BY chirp_id, position ASC;
+
+-- name: GetUnprocessedMedia :many
+SELECT * FROM media_files
+WHERE processed_at IS NULL
+ORDER BY created_at ASC
+LIMIT $1;
+
+-- name: SetMediaProcessed :exec
+UPDATE media_files
+SET width = $2,
+height = $3,
+blurhash = $4,
+processed_at = $5
+WHERE id = $1;
+
+-- name: UpsertMediaVariant :exec
+INSERT INTO media_variants(media_id, name, storage_key, content_type, width, height, size_bytes)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7
+)
+ON CONFLICT (media_id, name) DO UPDATE
+SET storage_key = EXCLUDED.storage_key,
+content_type = EXCLUDED.content_type,
+width = EXCLUDED.width,
+height = EXCLUDED.height,
+size_bytes = EXCLUDED.size_bytes;
+
+-- name: GetVariantsForMedia :many
+SELECT * FROM media_variants
+WHERE media_id = ANY(sqlc.arg(media_ids)::uuid[])
+ORDER BY media_id, name;
+
+-- name: DeleteMediaForChirp :exec
+DELETE FROM media_files
+WHERE chirp_id = $1;
+
+-- name: MediaKeyInUse :one
+SELECT EXISTS(
+    SELECT 1 FROM media_files WHERE storage_key = $1
+    UNION ALL
+    SELECT 1 FROM media_variants WHERE storage_key = $1
+);
+
+-- name: LockMediaKey :exec
+SELECT pg_advisory_xact_lock(hashtext(sqlc.arg(storage_key)::text));
@@ -0,0 +1,21 @@
+-- name: CreateNotification :exec
+INSERT INTO notifications(id, created_at, user_id, kind, chirp_id)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+);
+
+-- name: GetNotificationsPage :many
+SELECT * FROM notifications
+WHERE user_id = sqlc.arg(user_id)
+AND (created_at, id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: MarkNotificationsRead :exec
+UPDATE notifications
+SET read_at = $2
+WHERE user_id = $1 AND read_at IS NULL;
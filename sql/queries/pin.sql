@@ -0,0 +1,22 @@
+-- name: PinChirp :execrows
+INSERT INTO pinned_chirps(user_id, chirp_id, created_at)
+SELECT sqlc.arg(user_id), sqlc.arg(chirp_id), sqlc.arg(created_at)
+WHERE (
+    SELECT COUNT(*) FROM pinned_chirps
+    WHERE user_id = sqlc.arg(user_id)
+) < sqlc.arg(max_pins)::bigint
+ON CONFLICT DO NOTHING;
+
+-- name: UnpinChirp :exec
+DELETE FROM pinned_chirps
+WHERE user_id = $1 AND chirp_id = $2;
+
+-- name: GetPinnedChirps :many
+SELECT chirps.* FROM pinned_chirps
+JOIN chirps ON chirps.id = pinned_chirps.chirp_id
+WHERE pinned_chirps.user_id = $1
+ORDER BY pinned_chirps.created_at DESC;
+
+-- name: GetPinnedChirpIDs :many
+SELECT chirp_id FROM pinned_chirps
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]);
@@ -0,0 +1,60 @@
+-- name: CreatePoll :one
+INSERT INTO polls(chirp_id, created_at, multiple, closes_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4
+)
+RETURNING *;
+
+-- name: CreatePollOption :exec
+INSERT INTO poll_options(chirp_id, position, title)
+VALUES (
+    $1,
+    $2,
+    $3
+);
+
+-- name: GetPoll :one
+SELECT *, closes_at <= sqlc.arg(now)::timestamp AS closed FROM polls
+WHERE chirp_id = sqlc.arg(chirp_id);
+
+-- name: GetPollsForChirps :many
+SELECT *, closes_at <= sqlc.arg(now)::timestamp AS closed FROM polls
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]);
+
+-- name: GetPollOptionsForChirps :many
+SELECT * FROM poll_options
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+ORDER BY chirp_id, position ASC;
+
+-- name: GetPollTallies :many
+SELECT v.chirp_id, c.position::integer AS position, COUNT(*) AS votes
+FROM poll_votes v, unnest(v.choices) AS c(position)
+WHERE v.chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+GROUP BY v.chirp_id, c.position;
+
+-- name: GetPollVotesByUser :many
+SELECT chirp_id, choices FROM poll_votes
+WHERE user_id = sqlc.arg(user_id)
+AND chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]);
+
+-- name: CreatePollVote :execrows
+INSERT INTO poll_votes(chirp_id, user_id, created_at, choices)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4
+)
+ON CONFLICT DO NOTHING;
+
+-- name: ClosePolls :many
+UPDATE polls
+SET closed_at = sqlc.arg(now)::timestamp
+FROM chirps
+WHERE polls.chirp_id = chirps.id
+AND polls.closed_at IS NULL
+AND polls.closes_at <= sqlc.arg(now)::timestamp
+RETURNING polls.chirp_id, chirps.user_id;
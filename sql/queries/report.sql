@@ -0,0 +1,52 @@
+-- name: CreateReport :one
+INSERT INTO reports(id, created_at, updated_at, reporter_id, chirp_id, author_id, reason, details)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8
+)
+RETURNING *;
+
+-- name: GetReport :one
+SELECT * FROM reports
+WHERE id = $1;
+
+-- name: GetReportsByStatus :many
+SELECT * FROM reports
+WHERE status = $1
+ORDER BY created_at ASC;
+
+-- name: SetReportStatus :exec
+UPDATE reports
+SET status = $2,
+updated_at = $3
+WHERE id = $1;
+
+-- name: ResolveReportsForChirp :exec
+UPDATE reports
+SET status = $2,
+updated_at = $3
+WHERE chirp_id = $1 AND status = 'open';
+
+-- name: CreateModerationAction :one
+INSERT INTO moderation_actions(id, created_at, moderator_id, action, report_id, chirp_id, target_user_id, note)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8
+)
+RETURNING *;
+
+-- name: GetModerationActions :many
+SELECT * FROM moderation_actions
+ORDER BY created_at DESC;
@@ -0,0 +1,53 @@
+-- name: GetSubscription :one
+SELECT * FROM subscriptions
+WHERE user_id = $1;
+
+-- name: UpsertSubscription :one
+INSERT INTO subscriptions(user_id, created_at, updated_at, plan, status, current_period_start, current_period_end, grace_until, canceled_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9
+)
+ON CONFLICT (user_id) DO UPDATE
+SET updated_at = EXCLUDED.updated_at,
+plan = EXCLUDED.plan,
+status = EXCLUDED.status,
+current_period_start = EXCLUDED.current_period_start,
+current_period_end = EXCLUDED.current_period_end,
+grace_until = EXCLUDED.grace_until,
+canceled_at = EXCLUDED.canceled_at
+RETURNING *;
+
+-- name: SyncChirpyRed :exec
+UPDATE users
+SET is_chirpy_red = EXISTS (
+    SELECT 1 FROM subscriptions
+    WHERE subscriptions.user_id = users.id
+    AND subscriptions.status IN ('active', 'past_due', 'canceled')
+    AND COALESCE(subscriptions.grace_until, subscriptions.current_period_end) > $2
+),
+updated_at = $2
+WHERE users.id = $1;
+
+-- name: ExpireSubscriptions :many
+WITH expired AS (
+    UPDATE subscriptions
+    SET status = 'expired',
+    updated_at = $1
+    WHERE status IN ('active', 'past_due', 'canceled')
+    AND COALESCE(grace_until, current_period_end) <= $1
+    RETURNING user_id
+)
+UPDATE users
+SET is_chirpy_red = false,
+updated_at = $1
+FROM expired
+WHERE users.id = expired.user_id
+RETURNING users.id;
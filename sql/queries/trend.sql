@@ -0,0 +1,30 @@
+-- name: GetHashtagActivity :many
+SELECT tags.tag::text AS tag,
+    COUNT(DISTINCT chirps.user_id) FILTER (WHERE chirps.created_at >= sqlc.arg(window_start)) AS recent_authors,
+    COUNT(DISTINCT chirps.user_id) FILTER (WHERE chirps.created_at < sqlc.arg(window_start)) AS baseline_authors
+FROM chirps
+JOIN users ON users.id = chirps.user_id
+CROSS JOIN LATERAL (
+    SELECT DISTINCT lower(m[1]) AS tag
+    FROM regexp_matches(chirps.body, '#([[:alnum:]_]+)', 'g') AS m
+) AS tags
+WHERE chirps.created_at >= sqlc.arg(baseline_start)
+    AND chirps.created_at <= sqlc.arg(now)
+    AND chirps.hidden_at IS NULL
+    AND chirps.visibility = 'public'
+    AND users.suspended_at IS NULL
+GROUP BY tags.tag
+HAVING COUNT(DISTINCT chirps.user_id) FILTER (WHERE chirps.created_at >= sqlc.arg(window_start)) >= sqlc.arg(min_authors)::bigint;
+
+-- name: GetPublicChirpsForHashtag :many
+SELECT chirps.* FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.visibility = 'public'
+    AND chirps.hidden_at IS NULL
+    AND users.suspended_at IS NULL
+    AND EXISTS (
+        SELECT 1 FROM regexp_matches(chirps.body, '#([[:alnum:]_]+)', 'g') AS m
+        WHERE lower(m[1]) = lower(sqlc.arg(tag)::text)
+    )
+ORDER BY chirps.created_at DESC
+LIMIT sqlc.arg(max_items)::int;
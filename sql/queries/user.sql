@@ -10,7 +10,7 @@ VALUES (
 RETURNING *;
 
 -- name: ResetUsers :exec
-TRUNCATE refresh_tokens, chirps, users;
+TRUNCATE refresh_tokens, chirps, users CASCADE;
 
 -- name: GetHashedPass :one
 SELECT email, hashed_password, id, created_at, updated_at, is_chirpy_red
@@ -33,4 +33,20 @@ WHERE id = $1;
 -- name: GetUser :exec
 SELECT email, id
 FROM users
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1;
+
+-- name: GetUserByID :one
+SELECT * FROM users
+WHERE id = $1;
+
+-- name: SetUserSuspended :exec
+UPDATE users
+SET suspended_at = $2,
+updated_at = $3
+WHERE id = $1;
+
+-- name: SetExpandSensitive :exec
+UPDATE users
+SET expand_sensitive = $2,
+updated_at = $3
+WHERE id = $1;
@@ -0,0 +1,129 @@
+-- name: CreateWebhookEndpoint :one
+INSERT INTO webhook_endpoints(id, created_at, updated_at, user_id, url, secret, events)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7
+)
+RETURNING *;
+
+-- name: CountWebhookEndpoints :one
+SELECT COUNT(*) FROM webhook_endpoints
+WHERE user_id = $1;
+
+-- name: GetWebhookEndpoints :many
+SELECT * FROM webhook_endpoints
+WHERE user_id = $1
+ORDER BY created_at ASC;
+
+-- name: GetWebhookEndpoint :one
+SELECT * FROM webhook_endpoints
+WHERE id = $1 AND user_id = $2;
+
+-- name: UpdateWebhookEndpoint :one
+UPDATE webhook_endpoints
+SET url = $3,
+events = $4,
+disabled_at = $5,
+failure_count = $6,
+updated_at = $7
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeleteWebhookEndpoint :execrows
+DELETE FROM webhook_endpoints
+WHERE id = $1 AND user_id = $2;
+
+-- name: GetWebhookEndpointsForEvent :many
+SELECT * FROM webhook_endpoints
+WHERE user_id = sqlc.arg(user_id)
+AND sqlc.arg(event)::text = ANY(events)
+AND disabled_at IS NULL;
+
+-- name: RecordWebhookSuccess :exec
+UPDATE webhook_endpoints
+SET failure_count = 0
+WHERE id = $1;
+
+-- name: RecordWebhookFailure :one
+UPDATE webhook_endpoints
+SET failure_count = failure_count + 1
+WHERE id = $1
+RETURNING failure_count;
+
+-- name: DisableWebhookEndpoint :exec
+UPDATE webhook_endpoints
+SET disabled_at = $2,
+updated_at = $2
+WHERE id = $1 AND disabled_at IS NULL;
+
+-- name: CreateWebhookDelivery :one
+INSERT INTO webhook_deliveries(id, created_at, endpoint_id, event_id, event, payload, next_attempt_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7
+)
+RETURNING *;
+
+-- name: ClaimDueWebhookDeliveries :many
+SELECT webhook_deliveries.*, webhook_endpoints.url, webhook_endpoints.secret, webhook_endpoints.user_id
+FROM webhook_deliveries
+JOIN webhook_endpoints ON webhook_endpoints.id = webhook_deliveries.endpoint_id
+WHERE webhook_deliveries.status = 'pending'
+AND webhook_deliveries.next_attempt_at <= $1
+AND webhook_endpoints.disabled_at IS NULL
+ORDER BY webhook_deliveries.next_attempt_at ASC
+LIMIT $2
+FOR UPDATE OF webhook_deliveries SKIP LOCKED;
+
+-- name: LeaseWebhookDeliveries :exec
+UPDATE webhook_deliveries
+SET next_attempt_at = sqlc.arg(next_attempt_at)
+WHERE id = ANY(sqlc.arg(ids)::uuid[]);
+
+-- name: RecordWebhookAttempt :exec
+UPDATE webhook_deliveries
+SET status = $2,
+attempts = attempts + 1,
+next_attempt_at = $3,
+response_code = $4,
+last_error = $5,
+completed_at = $6
+WHERE id = $1;
+
+-- name: GetWebhookDeliveriesPage :many
+SELECT * FROM webhook_deliveries
+WHERE endpoint_id = sqlc.arg(endpoint_id)
+AND (created_at, id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: GetWebhookDelivery :one
+SELECT * FROM webhook_deliveries
+WHERE id = $1 AND endpoint_id = $2;
+
+-- name: DeleteOldWebhookDeliveries :exec
+DELETE FROM webhook_deliveries
+WHERE created_at < $1 AND status <> 'pending';
+
+-- name: RecordWebhookEvent :execrows
+INSERT INTO webhook_events(id, event, received_at)
+VALUES (
+    $1,
+    $2,
+    $3
+)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteOldWebhookEvents :exec
+DELETE FROM webhook_events
+WHERE received_at < $1;
@@ -0,0 +1,46 @@
+-- +goose Up
+CREATE TABLE conversations(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    created_by UUID NOT NULL,
+    FOREIGN KEY(created_by)
+    REFERENCES users(id)
+    ON DELETE CASCADE
+);
+
+CREATE TABLE conversation_members(
+    conversation_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    joined_at TIMESTAMP NOT NULL,
+    last_read_at TIMESTAMP,
+    PRIMARY KEY(conversation_id, user_id),
+    FOREIGN KEY(conversation_id)
+    REFERENCES conversations(id)
+    ON DELETE CASCADE,
+    FOREIGN KEY(user_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE
+);
+
+CREATE TABLE messages(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    conversation_id UUID NOT NULL,
+    sender_id UUID NOT NULL,
+    body TEXT NOT NULL,
+    FOREIGN KEY(conversation_id)
+    REFERENCES conversations(id)
+    ON DELETE CASCADE,
+    FOREIGN KEY(sender_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE
+);
+
+CREATE INDEX messages_conversation_created_idx ON messages(conversation_id, created_at DESC, id DESC);
+
+-- +goose Down
+DROP TABLE messages;
+DROP TABLE conversation_members;
+DROP TABLE conversations;
@@ -0,0 +1,42 @@
+-- +goose Up
+CREATE TABLE user_blocks(
+    blocker_id UUID NOT NULL,
+    blocked_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY(blocker_id, blocked_id),
+    FOREIGN KEY(blocker_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE,
+    FOREIGN KEY(blocked_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE
+);
+
+CREATE TABLE user_mutes(
+    muter_id UUID NOT NULL,
+    muted_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY(muter_id, muted_id),
+    FOREIGN KEY(muter_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE,
+    FOREIGN KEY(muted_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE
+);
+
+CREATE TABLE muted_words(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL,
+    word TEXT NOT NULL,
+    expires_at TIMESTAMP,
+    FOREIGN KEY(user_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE muted_words;
+DROP TABLE user_mutes;
+DROP TABLE user_blocks;
@@ -0,0 +1,56 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN is_moderator BOOLEAN NOT NULL DEFAULT false,
+ADD COLUMN suspended_at TIMESTAMP;
+
+ALTER TABLE chirps
+ADD COLUMN hidden_at TIMESTAMP;
+
+CREATE TABLE reports(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    reporter_id UUID NOT NULL,
+    chirp_id UUID,
+    author_id UUID NOT NULL,
+    reason TEXT NOT NULL,
+    details TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'open',
+    FOREIGN KEY(reporter_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE,
+    FOREIGN KEY(chirp_id)
+    REFERENCES chirps(id)
+    ON DELETE SET NULL,
+    FOREIGN KEY(author_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE
+);
+
+CREATE TABLE moderation_actions(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    moderator_id UUID NOT NULL,
+    action TEXT NOT NULL,
+    report_id UUID,
+    chirp_id UUID,
+    target_user_id UUID,
+    note TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY(moderator_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE,
+    FOREIGN KEY(report_id)
+    REFERENCES reports(id)
+    ON DELETE SET NULL
+);
+
+-- +goose Down
+DROP TABLE moderation_actions;
+DROP TABLE reports;
+
+ALTER TABLE chirps
+DROP COLUMN hidden_at;
+
+ALTER TABLE users
+DROP COLUMN suspended_at,
+DROP COLUMN is_moderator;
@@ -0,0 +1,27 @@
+-- +goose Up
+CREATE TABLE filter_rules(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    pattern TEXT NOT NULL,
+    kind TEXT NOT NULL DEFAULT 'word',
+    action TEXT NOT NULL DEFAULT 'mask',
+    reason TEXT NOT NULL DEFAULT ''
+);
+
+INSERT INTO filter_rules(id, created_at, pattern)
+VALUES
+    (gen_random_uuid(), NOW(), 'kerfuffle'),
+    (gen_random_uuid(), NOW(), 'sharbert'),
+    (gen_random_uuid(), NOW(), 'fornax');
+
+ALTER TABLE reports
+ALTER COLUMN reporter_id DROP NOT NULL;
+
+-- +goose Down
+DELETE FROM reports
+WHERE reporter_id IS NULL;
+
+ALTER TABLE reports
+ALTER COLUMN reporter_id SET NOT NULL;
+
+DROP TABLE filter_rules;
@@ -0,0 +1,34 @@
+-- +goose Up
+ALTER TABLE media_files
+ADD COLUMN width INTEGER,
+ADD COLUMN height INTEGER,
+ADD COLUMN blurhash TEXT,
+ADD COLUMN processed_at TIMESTAMP;
+
+CREATE INDEX media_files_unprocessed_idx ON media_files(created_at) WHERE processed_at IS NULL;
+CREATE INDEX media_files_storage_key_idx ON media_files(storage_key);
+
+CREATE TABLE media_variants(
+    media_id UUID NOT NULL,
+    name TEXT NOT NULL,
+    storage_key TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    PRIMARY KEY(media_id, name),
+    FOREIGN KEY(media_id)
+    REFERENCES media_files(id)
+    ON DELETE CASCADE
+);
+
+CREATE INDEX media_variants_storage_key_idx ON media_variants(storage_key);
+
+-- +goose Down
+DROP TABLE media_variants;
+
+ALTER TABLE media_files
+DROP COLUMN width,
+DROP COLUMN height,
+DROP COLUMN blurhash,
+DROP COLUMN processed_at;
@@ -0,0 +1,44 @@
+-- +goose Up
+CREATE TABLE polls(
+    chirp_id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    multiple BOOLEAN NOT NULL DEFAULT false,
+    closes_at TIMESTAMP NOT NULL,
+    closed_at TIMESTAMP,
+    FOREIGN KEY(chirp_id)
+    REFERENCES chirps(id)
+    ON DELETE CASCADE
+);
+
+CREATE INDEX polls_open_idx ON polls(closes_at) WHERE closed_at IS NULL;
+
+CREATE TABLE poll_options(
+    chirp_id UUID NOT NULL,
+    position INTEGER NOT NULL,
+    title TEXT NOT NULL,
+    PRIMARY KEY(chirp_id, position),
+    FOREIGN KEY(chirp_id)
+    REFERENCES polls(chirp_id)
+    ON DELETE CASCADE
+);
+
+-- One row per voter, so a user can only vote once. choices holds the
+-- positions of the options they picked.
+CREATE TABLE poll_votes(
+    chirp_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    choices INTEGER[] NOT NULL,
+    PRIMARY KEY(chirp_id, user_id),
+    FOREIGN KEY(chirp_id)
+    REFERENCES polls(chirp_id)
+    ON DELETE CASCADE,
+    FOREIGN KEY(user_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE poll_votes;
+DROP TABLE poll_options;
+DROP TABLE polls;
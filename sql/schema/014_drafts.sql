@@ -0,0 +1,19 @@
+-- +goose Up
+-- A draft with a publish_at time is a scheduled chirp.
+CREATE TABLE drafts(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL,
+    body TEXT NOT NULL,
+    publish_at TIMESTAMP,
+    FOREIGN KEY(user_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE
+);
+
+CREATE INDEX drafts_user_idx ON drafts(user_id);
+CREATE INDEX drafts_publish_at_idx ON drafts(publish_at) WHERE publish_at IS NOT NULL;
+
+-- +goose Down
+DROP TABLE drafts;
@@ -0,0 +1,32 @@
+-- +goose Up
+CREATE TABLE follows(
+    follower_id UUID NOT NULL,
+    followee_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY(follower_id, followee_id),
+    FOREIGN KEY(follower_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE,
+    FOREIGN KEY(followee_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE
+);
+
+CREATE INDEX follows_followee_idx ON follows(followee_id);
+
+ALTER TABLE chirps
+ADD COLUMN visibility TEXT NOT NULL DEFAULT 'public'
+CHECK (visibility IN ('public', 'unlisted', 'followers', 'private'));
+
+ALTER TABLE drafts
+ADD COLUMN visibility TEXT NOT NULL DEFAULT 'public'
+CHECK (visibility IN ('public', 'unlisted', 'followers', 'private'));
+
+-- +goose Down
+ALTER TABLE drafts
+DROP COLUMN visibility;
+
+ALTER TABLE chirps
+DROP COLUMN visibility;
+
+DROP TABLE follows;
@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE pinned_chirps(
+    user_id UUID NOT NULL,
+    chirp_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY(user_id, chirp_id),
+    FOREIGN KEY(user_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE,
+    FOREIGN KEY(chirp_id)
+    REFERENCES chirps(id)
+    ON DELETE CASCADE
+);
+
+CREATE INDEX pinned_chirps_chirp_idx ON pinned_chirps(chirp_id);
+
+-- +goose Down
+DROP TABLE pinned_chirps;
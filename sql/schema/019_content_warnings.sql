@@ -0,0 +1,23 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD COLUMN content_warning TEXT NOT NULL DEFAULT '',
+ADD COLUMN sensitive BOOLEAN NOT NULL DEFAULT false;
+
+ALTER TABLE drafts
+ADD COLUMN content_warning TEXT NOT NULL DEFAULT '',
+ADD COLUMN sensitive BOOLEAN NOT NULL DEFAULT false;
+
+ALTER TABLE users
+ADD COLUMN expand_sensitive BOOLEAN NOT NULL DEFAULT false;
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN expand_sensitive;
+
+ALTER TABLE drafts
+DROP COLUMN sensitive,
+DROP COLUMN content_warning;
+
+ALTER TABLE chirps
+DROP COLUMN sensitive,
+DROP COLUMN content_warning;
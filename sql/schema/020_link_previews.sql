@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE link_previews(
+    url TEXT PRIMARY KEY,
+    fetched_at TIMESTAMP NOT NULL,
+    ok BOOLEAN NOT NULL,
+    title TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    image_url TEXT NOT NULL DEFAULT '',
+    site_name TEXT NOT NULL DEFAULT ''
+);
+
+-- +goose Down
+DROP TABLE link_previews;
@@ -0,0 +1,85 @@
+-- +goose Up
+CREATE TABLE actor_keys(
+    user_id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    private_key_pem TEXT NOT NULL,
+    public_key_pem TEXT NOT NULL,
+    FOREIGN KEY(user_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE
+);
+
+CREATE TABLE remote_actors(
+    id TEXT PRIMARY KEY,
+    fetched_at TIMESTAMP NOT NULL,
+    inbox_url TEXT NOT NULL,
+    shared_inbox_url TEXT NOT NULL DEFAULT '',
+    public_key_id TEXT NOT NULL,
+    public_key_pem TEXT NOT NULL
+);
+
+CREATE TABLE remote_followers(
+    user_id UUID NOT NULL,
+    actor_id TEXT NOT NULL,
+    activity_id TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY(user_id, actor_id),
+    FOREIGN KEY(user_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE,
+    FOREIGN KEY(actor_id)
+    REFERENCES remote_actors(id)
+    ON DELETE CASCADE
+);
+
+CREATE TABLE remote_follows(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL,
+    actor_id TEXT NOT NULL,
+    accepted_at TIMESTAMP,
+    UNIQUE(user_id, actor_id),
+    FOREIGN KEY(user_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE,
+    FOREIGN KEY(actor_id)
+    REFERENCES remote_actors(id)
+    ON DELETE CASCADE
+);
+
+CREATE TABLE remote_likes(
+    chirp_id UUID NOT NULL,
+    actor_id TEXT NOT NULL,
+    activity_id TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY(chirp_id, actor_id),
+    FOREIGN KEY(chirp_id)
+    REFERENCES chirps(id)
+    ON DELETE CASCADE,
+    FOREIGN KEY(actor_id)
+    REFERENCES remote_actors(id)
+    ON DELETE CASCADE
+);
+
+CREATE TABLE federation_deliveries(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    sender_id UUID NOT NULL,
+    inbox_url TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL,
+    FOREIGN KEY(sender_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE
+);
+
+CREATE INDEX federation_deliveries_due_idx ON federation_deliveries(next_attempt_at);
+
+-- +goose Down
+DROP TABLE federation_deliveries;
+DROP TABLE remote_likes;
+DROP TABLE remote_follows;
+DROP TABLE remote_followers;
+DROP TABLE remote_actors;
+DROP TABLE actor_keys;
@@ -0,0 +1,8 @@
+-- +goose Up
+CREATE TABLE deleted_chirps(
+    id UUID PRIMARY KEY,
+    deleted_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE deleted_chirps;
@@ -0,0 +1,24 @@
+-- +goose Up
+CREATE TABLE subscriptions(
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    plan TEXT NOT NULL,
+    status TEXT NOT NULL,
+    current_period_start TIMESTAMP NOT NULL,
+    current_period_end TIMESTAMP NOT NULL,
+    grace_until TIMESTAMP,
+    canceled_at TIMESTAMP
+);
+
+CREATE INDEX subscriptions_status_idx ON subscriptions(status);
+
+-- Users upgraded before subscriptions were tracked get a period that runs
+-- until Polka's next renewal would normally arrive.
+INSERT INTO subscriptions(user_id, created_at, updated_at, plan, status, current_period_start, current_period_end)
+SELECT id, NOW(), NOW(), 'red', 'active', NOW(), NOW() + INTERVAL '30 days'
+FROM users
+WHERE is_chirpy_red;
+
+-- +goose Down
+DROP TABLE subscriptions;
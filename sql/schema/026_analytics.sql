@@ -0,0 +1,30 @@
+-- +goose Up
+CREATE TABLE chirp_stats_daily(
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    impressions INTEGER NOT NULL DEFAULT 0,
+    engagements INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (chirp_id, day)
+);
+
+CREATE TABLE user_stats_daily(
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    profile_visits INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, day)
+);
+
+-- Who has already been counted today, so each viewer counts once per
+-- chirp or profile per day. Rows are only needed for the current day.
+CREATE TABLE analytics_seen(
+    kind TEXT NOT NULL,
+    subject_id UUID NOT NULL,
+    viewer TEXT NOT NULL,
+    day DATE NOT NULL,
+    PRIMARY KEY (day, kind, subject_id, viewer)
+);
+
+-- +goose Down
+DROP TABLE analytics_seen;
+DROP TABLE user_stats_daily;
+DROP TABLE chirp_stats_daily;
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tristenkelly/chirpy/internal/database"
+	"github.com/tristenkelly/chirpy/internal/webhook"
+)
+
+// Events users can subscribe a webhook endpoint to.
+const (
+	webhookChirpCreated = "chirp.created"
+	webhookChirpDeleted = "chirp.deleted"
+	webhookFollowerNew  = "follower.new"
+	webhookMention      = "mention"
+)
+
+var webhookEvents = map[string]bool{
+	webhookChirpCreated: true,
+	webhookChirpDeleted: true,
+	webhookFollowerNew:  true,
+	webhookMention:      true,
+}
+
+const (
+	webhookInterval     = 10 * time.Second
+	webhookBatchSize    = 20
+	maxWebhookAttempts  = 8
+	maxWebhookFailures  = 20
+	webhookLogRetention = 30 * 24 * time.Hour
+	maxWebhookErrorLen  = 500
+	// webhookLease is how long a claimed delivery is left to the worker
+	// that claimed it. It covers a full batch of requests timing out.
+	webhookLease = 5 * time.Minute
+
+	webhookPending   = "pending"
+	webhookSucceeded = "succeeded"
+	webhookFailed    = "failed"
+
+	notificationWebhookDisabled = "webhook_disabled"
+)
+
+// Users have no handles, so a mention is @ followed by the user's ID, the
+// same name they have on the fediverse.
+var mentionPattern = regexp.MustCompile(`@([0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})\b`)
+
+// webhookPayload is the body of every delivery. ID identifies the event and
+// is the same across endpoints and redeliveries, so receivers can drop
+// duplicates.
+type webhookPayload struct {
+	ID        uuid.UUID `json:"id"`
+	Event     string    `json:"event"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// emitWebhook queues an event for each of the user's endpoints subscribed
+// to it.
+func (cfg *apiConfig) emitWebhook(ctx context.Context, userID uuid.UUID, event string, data any) {
+	endpoints, err := cfg.db.GetWebhookEndpointsForEvent(ctx, database.GetWebhookEndpointsForEventParams{
+		UserID: userID,
+		Event:  event,
+	})
+	if err != nil {
+		log.Printf("error getting webhook endpoints for %v: %v", userID, err)
+		return
+	}
+	if len(endpoints) == 0 {
+		return
+	}
+
+	now := time.Now()
+	payload := webhookPayload{ID: uuid.New(), Event: event, CreatedAt: now, Data: data}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("error marshalling %s webhook: %v", event, err)
+		return
+	}
+	for _, endpoint := range endpoints {
+		_, err := cfg.db.CreateWebhookDelivery(ctx, database.CreateWebhookDeliveryParams{
+			ID:            uuid.New(),
+			CreatedAt:     now,
+			EndpointID:    endpoint.ID,
+			EventID:       payload.ID,
+			Event:         event,
+			Payload:       string(body),
+			NextAttemptAt: now,
+		})
+		if err != nil {
+			log.Printf("error queueing %s webhook for %v: %v", event, endpoint.ID, err)
+		}
+	}
+}
+
+// emitChirpCreated sends chirp.created to the author and mention to each
+// user the chirp mentions who is allowed to see it.
+func (cfg *apiConfig) emitChirpCreated(ctx context.Context, chirp database.Chirp) {
+	resp, err := cfg.chirpResponses(ctx, chirp.UserID, []database.Chirp{chirp})
+	if err != nil {
+		log.Printf("error building chirp %v for webhooks: %v", chirp.ID, err)
+		return
+	}
+	cfg.emitWebhook(ctx, chirp.UserID, webhookChirpCreated, resp[0])
+
+	seen := map[uuid.UUID]bool{chirp.UserID: true}
+	for _, m := range mentionPattern.FindAllStringSubmatch(chirp.Body, -1) {
+		userID, err := uuid.Parse(m[1])
+		if err != nil || seen[userID] {
+			continue
+		}
+		seen[userID] = true
+		if _, err := cfg.db.GetUserByID(ctx, userID); err != nil {
+			continue
+		}
+		filter, err := cfg.loadChirpFilter(ctx, userID, true)
+		if err != nil {
+			log.Printf("error loading chirp filter for %v: %v", userID, err)
+			continue
+		}
+		blocked, err := cfg.isBlocked(ctx, userID, chirp.UserID)
+		if err != nil {
+			log.Printf("error checking block: %v", err)
+			continue
+		}
+		if blocked || !filter.canSee(chirp) {
+			continue
+		}
+		mentioned, err := cfg.chirpResponses(ctx, userID, []database.Chirp{chirp})
+		if err != nil {
+			log.Printf("error building chirp %v for webhooks: %v", chirp.ID, err)
+			continue
+		}
+		cfg.emitWebhook(ctx, userID, webhookMention, map[string]any{"chirp": mentioned[0]})
+	}
+}
+
+func (cfg *apiConfig) emitChirpDeleted(ctx context.Context, chirp database.Chirp) {
+	cfg.emitWebhook(ctx, chirp.UserID, webhookChirpDeleted, map[string]any{
+		"id":      chirp.ID,
+		"user_id": chirp.UserID,
+	})
+}
+
+// runWebhookWorker sends queued webhook deliveries, retrying failures with
+// exponential backoff, and prunes the delivery log and the record of
+// incoming events.
+func (cfg *apiConfig) runWebhookWorker(ctx context.Context) {
+	ticker := time.NewTicker(webhookInterval)
+	defer ticker.Stop()
+	for {
+		// A full batch means there may be more due right away.
+		for cfg.sendDueWebhooks(ctx) == webhookBatchSize {
+			if ctx.Err() != nil {
+				return
+			}
+		}
+		err := cfg.db.DeleteOldWebhookDeliveries(ctx, time.Now().Add(-webhookLogRetention))
+		if err != nil {
+			log.Printf("error pruning webhook deliveries: %v", err)
+		}
+		// Incoming event IDs only need keeping for as long as the sender
+		// might retry them.
+		err = cfg.db.DeleteOldWebhookEvents(ctx, time.Now().Add(-webhookLogRetention))
+		if err != nil {
+			log.Printf("error pruning webhook events: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sendDueWebhooks sends one batch of due deliveries and returns how many it
+// claimed. Claiming pushes next_attempt_at out by webhookLease, so with
+// several replicas running each delivery is attempted by one of them at a
+// time, and no row locks are held while requests are in flight.
+func (cfg *apiConfig) sendDueWebhooks(ctx context.Context) int {
+	due, err := cfg.claimDueWebhooks(ctx)
+	if err != nil {
+		log.Printf("error claiming webhook deliveries: %v", err)
+		return 0
+	}
+
+	// Results are still recorded once shutdown has begun, so a delivery
+	// that went out isn't sent again when its lease runs out.
+	recordCtx := context.WithoutCancel(ctx)
+	for _, d := range due {
+		code, sendErr := cfg.sendWebhook(ctx, d)
+		if ctx.Err() != nil {
+			// Cut off by shutdown. This and the rest of the batch are
+			// retried when the lease expires, without counting an attempt.
+			break
+		}
+		cfg.recordWebhookResult(recordCtx, d, code, sendErr)
+	}
+	return len(due)
+}
+
+// claimDueWebhooks leases a batch of due deliveries to this worker.
+func (cfg *apiConfig) claimDueWebhooks(ctx context.Context) ([]database.ClaimDueWebhookDeliveriesRow, error) {
+	tx, err := cfg.sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	q := cfg.withTx(tx)
+
+	now := time.Now()
+	due, err := q.ClaimDueWebhookDeliveries(ctx, database.ClaimDueWebhookDeliveriesParams{
+		NextAttemptAt: now,
+		Limit:         webhookBatchSize,
+	})
+	if err != nil || len(due) == 0 {
+		return nil, err
+	}
+	ids := make([]uuid.UUID, len(due))
+	for i, d := range due {
+		ids[i] = d.ID
+	}
+	err = q.LeaseWebhookDeliveries(ctx, database.LeaseWebhookDeliveriesParams{
+		NextAttemptAt: now.Add(webhookLease),
+		Ids:           ids,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return due, nil
+}
+
+// recordWebhookResult stores the outcome of one attempt, scheduling a
+// retry with exponential backoff if it failed, and keeps the endpoint's
+// failure count.
+func (cfg *apiConfig) recordWebhookResult(ctx context.Context, d database.ClaimDueWebhookDeliveriesRow, code int, sendErr error) {
+	now := time.Now()
+	attempt := database.RecordWebhookAttemptParams{
+		ID:            d.ID,
+		Status:        webhookSucceeded,
+		NextAttemptAt: d.NextAttemptAt,
+		ResponseCode:  int32(code),
+		CompletedAt:   sql.NullTime{Time: now, Valid: true},
+	}
+	if sendErr != nil {
+		attempt.LastError = truncateRunes(sendErr.Error(), maxWebhookErrorLen)
+		if d.Attempts+1 >= maxWebhookAttempts {
+			attempt.Status = webhookFailed
+		} else {
+			attempt.Status = webhookPending
+			attempt.NextAttemptAt = now.Add(time.Minute << d.Attempts)
+			attempt.CompletedAt = sql.NullTime{}
+		}
+	}
+	if err := cfg.db.RecordWebhookAttempt(ctx, attempt); err != nil {
+		log.Printf("error recording webhook attempt %v: %v", d.ID, err)
+		return
+	}
+	cfg.metrics.Webhooks.WithLabelValues("outgoing", attempt.Status).Inc()
+
+	if sendErr == nil {
+		if err := cfg.db.RecordWebhookSuccess(ctx, d.EndpointID); err != nil {
+			log.Printf("error updating webhook endpoint %v: %v", d.EndpointID, err)
+		}
+		return
+	}
+	// An endpoint that keeps failing is switched off until its owner turns
+	// it back on.
+	failures, err := cfg.db.RecordWebhookFailure(ctx, d.EndpointID)
+	if err != nil {
+		log.Printf("error updating webhook endpoint %v: %v", d.EndpointID, err)
+		return
+	}
+	if failures != maxWebhookFailures {
+		return
+	}
+	err = cfg.db.DisableWebhookEndpoint(ctx, database.DisableWebhookEndpointParams{
+		ID:         d.EndpointID,
+		DisabledAt: sql.NullTime{Time: now, Valid: true},
+	})
+	if err != nil {
+		log.Printf("error disabling webhook endpoint %v: %v", d.EndpointID, err)
+		return
+	}
+	log.Printf("disabled webhook endpoint %v after %d failures", d.EndpointID, maxWebhookFailures)
+	cfg.notify(ctx, d.UserID, notificationWebhookDisabled, uuid.NullUUID{})
+}
+
+// sendWebhook POSTs one delivery, signed at send time so the timestamp is
+// fresh on every attempt. It returns the response status, if there was one.
+func (cfg *apiConfig) sendWebhook(ctx context.Context, d database.ClaimDueWebhookDeliveriesRow) (int, error) {
+	body := []byte(d.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Chirpy-Webhooks/1.0")
+	req.Header.Set("Chirpy-Event", d.Event)
+	req.Header.Set("Chirpy-Delivery", d.ID.String())
+	req.Header.Set("Chirpy-Signature", webhook.Sign(d.Secret, time.Now(), body))
+
+	resp, err := cfg.webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint returned %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}